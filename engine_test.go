@@ -14,11 +14,13 @@ import (
 
 func TestAgainstItself(t *testing.T) {
 	bitboards.InitBitboards()
-	board.TranspositionTable = make(map[uint64]board.TranspositionEntry)
-	board.InitZobristTable()
+	board.TranspositionTable.Clear()
 	b := board.New()
 
 	for {
+		if b.GameState() != board.InProgress {
+			break
+		}
 		err := b.MakeMove(4)
 		if err != nil {
 			break
@@ -35,8 +37,7 @@ func TestAgainstHuman(t *testing.T) {
 
 	fmt.Println("Enter moves in standard chess notation (e.g., 'e2e4'), type 'exit' to quit:")
 	bitboards.InitBitboards()
-	board.TranspositionTable = make(map[uint64]board.TranspositionEntry)
-	board.InitZobristTable()
+	board.TranspositionTable.Clear()
 
 	for {
 		fmt.Print("Enter move: ")
@@ -0,0 +1,56 @@
+package bitboards
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	InitBitboards()
+	m.Run()
+}
+
+func TestMagicAttacksMatchRayWalk(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for sq := 0; sq < 64; sq++ {
+		for i := 0; i < 200; i++ {
+			occupied := BitBoard(random.Uint64() & random.Uint64())
+
+			assert.Equal(t, RookBitboard(New(sq)).Moves(0, occupied), RookAttacks(sq, occupied), "rook on %d", sq)
+			assert.Equal(t, BishopBitboard(New(sq)).Moves(0, occupied), BishopAttacks(sq, occupied), "bishop on %d", sq)
+			assert.Equal(t, QueenBitboard(New(sq)).Moves(0, occupied), QueenAttacks(sq, occupied), "queen on %d", sq)
+		}
+	}
+}
+
+var (
+	benchmarkOccupancy = BitBoard(0x42FF00100408FF24)
+	benchmarkSink      BitBoard
+)
+
+func BenchmarkRookAttacksMagic(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = RookAttacks(i&63, benchmarkOccupancy)
+	}
+}
+
+func BenchmarkRookAttacksRayWalk(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = RookBitboard(New(i&63)).Moves(0, benchmarkOccupancy)
+	}
+}
+
+func BenchmarkBishopAttacksMagic(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = BishopAttacks(i&63, benchmarkOccupancy)
+	}
+}
+
+func BenchmarkBishopAttacksRayWalk(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = BishopBitboard(New(i&63)).Moves(0, benchmarkOccupancy)
+	}
+}
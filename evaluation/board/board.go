@@ -2,6 +2,7 @@ package board
 
 import (
 	"fmt"
+	"math/bits"
 	"strings"
 
 	"engine/evaluation/board/bitboards"
@@ -55,8 +56,11 @@ type Board struct {
 
 	TurnBlack bool // Flag to indicate if it's black's turn to move
 
-	HalfTurn int // What is the half turn
-	Debug    bool
+	HalfTurn      int      // What is the half turn
+	HalfMoveClock int      // Half turns since the last capture or pawn move
+	Hash          uint64   // Zobrist hash of the position, kept up to date by makeMove and UndoMove
+	History       []uint64 // Hashes of the positions before each move made, oldest first
+	Debug         bool
 }
 
 func New() Board {
@@ -88,7 +92,7 @@ func New() Board {
 
 	turnBlack := false
 
-	return Board{
+	board := Board{
 		WhitePawns:           whitePawns,
 		BlackPawns:           blackPawns,
 		WhiteKnights:         whiteKnights,
@@ -112,6 +116,9 @@ func New() Board {
 		CastleWhiteQueenside: true,
 		TurnBlack:            turnBlack,
 	}
+	board.Hash = board.computeHash()
+
+	return board
 }
 
 func (board Board) Display() {
@@ -225,11 +232,34 @@ func (board Board) ToFEN() string {
 		rankStrings = append(rankStrings, rankString)
 	}
 
+	turn := "w"
 	if board.TurnBlack {
-		return strings.Join(rankStrings, "/") + " b - -"
-	} else {
-		return strings.Join(rankStrings, "/") + " w - -"
+		turn = "b"
+	}
+
+	castling := ""
+	if board.CastleWhiteKingside {
+		castling += "K"
+	}
+	if board.CastleWhiteQueenside {
+		castling += "Q"
+	}
+	if board.CastleBlackKingside {
+		castling += "k"
 	}
+	if board.CastleBlackQueenside {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+
+	enPassant := "-"
+	if board.EnPassantTarget != 0 {
+		enPassant = IndexToPosition(uint64(bits.TrailingZeros64(uint64(board.EnPassantTarget))))
+	}
+
+	return fmt.Sprintf("%s %s %s %s %d %d", strings.Join(rankStrings, "/"), turn, castling, enPassant, board.HalfMoveClock, board.HalfTurn/2+1)
 }
 
 func (b *Board) updateAggregateBitboards() {
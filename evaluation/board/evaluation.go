@@ -1,7 +1,6 @@
 package board
 
 import (
-	"fmt"
 	"math/bits"
 
 	"engine/evaluation/board/bitboards"
@@ -109,12 +108,14 @@ func (board Board) piecesInCentre() int8 {
 // IsAttacked
 
 func (board Board) IsStaleMate() bool {
-	if !board.IsAttacked(board.KingInPlayAndOpponentAttacks()) {
-		legalMoves := board.AvailableBlackMoves()
-		return len(legalMoves) == 0
+	if board.TurnBlack && board.isKingInCheck(board.BlackKing, false) {
+		return false
+	}
+	if !board.TurnBlack && board.isKingInCheck(board.WhiteKing, true) {
+		return false
 	}
 
-	return false
+	return len(board.LegalMoves()) == 0
 }
 
 func (board Board) KingSafetyBonus() int8 {
@@ -143,19 +144,45 @@ func (board Board) DynamicKingSafety() int8 {
 	return int8(float64(board.KingSafetyBonus()) * (0.5 + 0.5*phase))
 }
 
+// Evaluation breaks the static evaluation down into its terms, each seen from the side to move.
 type Evaluation struct {
-	material      int8
-	pawnPenalties int8
-	mobilityBonus int8
-	centreBonus   int8
-	safety        int8
-	knightBonus   int8
+	material      int32
+	pawnPenalties int32
+	mobilityBonus int32
+	centreBonus   int32
+	safety        int32
+	knightBonus   int32
+	hanging       int32
+}
+
+func (e Evaluation) Sum() int32 {
+	return e.material + e.pawnPenalties + e.mobilityBonus + e.centreBonus + e.safety + e.knightBonus + e.hanging
+}
+
+// Centipawns converts the evaluation to centipawns, a pawn being worth materialModifier.
+func (e Evaluation) Centipawns(materialModifier int8) int32 {
+	if materialModifier <= 0 {
+		return e.Sum()
+	}
+	return e.Sum() * 100 / int32(materialModifier)
+}
+
+// scale keeps factor/scaleNormal of every term.
+func (e Evaluation) scale(factor int32) Evaluation {
+	return Evaluation{
+		e.material * factor / scaleNormal,
+		e.pawnPenalties * factor / scaleNormal,
+		e.mobilityBonus * factor / scaleNormal,
+		e.centreBonus * factor / scaleNormal,
+		e.safety * factor / scaleNormal,
+		e.knightBonus * factor / scaleNormal,
+		e.hanging * factor / scaleNormal,
+	}
 }
 
-func (e Evaluation) Sum() int16 {
-	var sum int16
-	sum = sum + int16(e.material) + int16(e.pawnPenalties) + int16(e.mobilityBonus) + int16(e.centreBonus) + int16(e.safety) + int16(e.knightBonus)
-	return sum
+// negate turns the evaluation around to the other player's point of view.
+func (e Evaluation) negate() Evaluation {
+	return Evaluation{-e.material, -e.pawnPenalties, -e.mobilityBonus, -e.centreBonus, -e.safety, -e.knightBonus, -e.hanging}
 }
 
 func (board Board) IsCheckMate() bool {
@@ -177,11 +204,28 @@ func (board Board) IsCheckMate() bool {
 
 // isKingInCheck checks if a given king is in check
 func (board Board) isKingInCheck(king bitboards.KingBitboard, opponentBlack bool) bool {
-	kingPosition := king.BitBoard()
+	if king == 0 {
+		return false
+	}
 
 	// Determine if the king is attacked by any opponent piece
-	attacks := board.generateAttacks(opponentBlack)
-	return attacks&kingPosition != 0
+	return board.isSquareAttacked(bits.TrailingZeros64(uint64(king)), opponentBlack)
+}
+
+// inCheck reports whether the side to move is in check.
+func (board Board) inCheck() bool {
+	if board.TurnBlack {
+		return board.isKingInCheck(board.BlackKing, false)
+	}
+	return board.isKingInCheck(board.WhiteKing, true)
+}
+
+// hasNonPawnMaterial reports whether the side to move has a piece other than its king and pawns.
+func (board Board) hasNonPawnMaterial() bool {
+	if board.TurnBlack {
+		return uint64(board.BlackKnights)|uint64(board.BlackBishops)|uint64(board.BlackRooks)|uint64(board.BlackQueens) != 0
+	}
+	return uint64(board.WhiteKnights)|uint64(board.WhiteBishops)|uint64(board.WhiteRooks)|uint64(board.WhiteQueens) != 0
 }
 
 func (board Board) generateAttacks(opponentBlack bool) bitboards.BitBoard {
@@ -192,22 +236,10 @@ func (board Board) generateAttacks(opponentBlack bool) bitboards.BitBoard {
 	return board.AvailableWhiteAttacks()
 }
 
+// Evaluate returns the static evaluation from the point of view of the side to move.
+// Checkmate and stalemate are left to the search, which knows how far away they are.
 func (board Board) Evaluate(materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) Evaluation {
-	if board.IsCheckMate() {
-		// Checkmate detection
-		if board.TurnBlack {
-			return Evaluation{-128, -128, -128, -128, -128, -128}
-		} else {
-			return Evaluation{127, 127, 127, 127, 127, 127}
-		}
-	}
-
-	if board.IsStaleMate() {
-		// Stalemate detection
-		return Evaluation{0, 0, 0, 0, 0, 0}
-	}
-
-	material := (board.whiteMaterial() - board.blackMaterial()) * materialModifier // 8
+	material := int32(board.whiteMaterial()-board.blackMaterial()) * int32(materialModifier) // 8
 
 	doubled := board.calculateDoubledPawns()
 	blocked := board.calculateBlockedPawns()
@@ -216,17 +248,23 @@ func (board Board) Evaluate(materialModifier, mobilityModifier, centreModifier,
 	centre := board.piecesInCentre()
 	kingSafety := board.DynamicKingSafety()
 	misplacedKnights := board.knightsOnRim()
-	aggression := board.GamePhase()
-	fmt.Sprint(aggression)
+	hanging := int32(board.hangingPieces(true)-board.hangingPieces(false)) * hangingPenalty
 
-	pawnPenalties := doubled + blocked + isolated
-	mobilityBonus := mobilityModifier * mobility //-1
-	centreBonus := centreModifier * centre       //-4
-	// knightBonus := 0
+	pawnPenalties := int32(doubled + blocked + isolated)
+	mobilityBonus := int32(mobilityModifier) * int32(mobility) //-1
+	centreBonus := int32(centreModifier) * int32(centre)       //-4
+
+	// Scored for white, then turned around when black is to move
+	eval := Evaluation{material, int32(penaltyModifier) * pawnPenalties, -mobilityBonus, -centreBonus, int32(kingSafety) * 3, int32(misplacedKnights), hanging}
+
+	// Some endings are drawn whatever the material says
+	if scale := board.drawScale(); scale != scaleNormal {
+		eval = eval.scale(scale)
+	}
 
 	if board.TurnBlack {
-		return Evaluation{material, penaltyModifier * pawnPenalties, -mobilityBonus, -centreBonus, kingSafety * 3, misplacedKnights}
-	} else {
-		return Evaluation{-material, penaltyModifier * -pawnPenalties, mobilityBonus, centreBonus, -kingSafety * 3, misplacedKnights}
+		return eval.negate()
 	}
+
+	return eval
 }
@@ -0,0 +1,82 @@
+package board
+
+import (
+	"engine/evaluation/board/bitboards"
+)
+
+const (
+	singularMinDepth   = 6 // Shallower nodes are not worth the extra search
+	singularDepthSlack = 3 // How much shallower than the node the hash entry may be
+	singularMargin     = 2 // Centipawns per ply the other moves must stay below the hash score
+)
+
+// extension returns how many plies deeper to search move, which the board after returns: forcing moves are
+// searched one ply deeper so that the horizon does not cut their line short. These are checks that do not
+// give material away and, on the principal variation only, recaptures on the square the previous move
+// captured on and pushes of passed pawns to the last two ranks before promotion.
+func (board *Board) extension(move Move, after *Board, previous Move, pvNode bool) int {
+	switch {
+	case after.inCheck() && board.SEE(move) >= 0:
+		return 1
+	case !pvNode:
+		return 0
+	case capturedValue(move) > 0 && capturedValue(previous) > 0 && move.Destination == previous.Destination:
+		return 1
+	case board.isPassedPawnPush(move):
+		return 1
+	}
+	return 0
+}
+
+// isPassedPawnPush reports whether the move takes a passed pawn to the sixth or seventh rank.
+func (board *Board) isPassedPawnPush(move Move) bool {
+	piece := move.Piece
+	if piece == -1 {
+		piece = board.PieceAt(move.Source)
+	}
+	if piece != WhitePawn && piece != BlackPawn || move.MoveType == Promotion {
+		return false
+	}
+
+	black := piece == BlackPawn
+	rank := move.Destination / 8
+	if black && rank > 2 || !black && rank < 5 {
+		return false
+	}
+
+	return board.isPassed(move.Destination, black)
+}
+
+// isPassed reports whether no pawn of the opponent can stop or capture a pawn of the given side standing on square.
+func (board *Board) isPassed(square int, black bool) bool {
+	file, rank := square%8, square/8
+
+	files := bitboards.FileMask(file)
+	if file > 0 {
+		files |= bitboards.FileMask(file - 1)
+	}
+	if file < 7 {
+		files |= bitboards.FileMask(file + 1)
+	}
+
+	// The squares in front of the pawn, towards its promotion rank
+	ahead := ^bitboards.BitBoard(0) << (8 * (rank + 1))
+	if black {
+		ahead = bitboards.BitBoard(1)<<(8*rank) - 1
+	}
+
+	return files&ahead&board.side(!black).pawns == 0
+}
+
+// isSingular reports whether the hash move is the only good move of the position: searched to a reduced
+// depth without it, every other move fails low against a bound a little below the score stored for it.
+func (board *Board) isSingular(entry TranspositionEntry, depth, ply int, previous Move, s *search) bool {
+	score := scoreFromTT(entry.Score, ply)
+	bound := score - singularMargin*int32(depth)
+
+	s.excluded[ply] = entry.BestMove
+	score = board.negamax((depth-1)/2, bound-1, bound, ply, previous, s, new([]Move))
+	s.excluded[ply] = 0
+
+	return !s.aborted.Load() && score < bound
+}
@@ -0,0 +1,87 @@
+package board
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPassed(t *testing.T) {
+	b, err := FromFEN("4k3/8/1p6/P6P/8/2p5/6P1/4K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	assert.True(t, b.isPassed(39, false), "h5 has no black pawn ahead")
+	assert.False(t, b.isPassed(32, false), "b6 guards a6")
+	assert.True(t, b.isPassed(14, false), "the c3 pawn is on another file")
+	assert.True(t, b.isPassed(18, true), "c3 has no white pawn ahead")
+	assert.False(t, b.isPassed(41, true), "a5 stands in its way")
+}
+
+func TestExtension(t *testing.T) {
+	// Qe8 checks, d6d7 pushes a passed pawn to the seventh rank and the pawns trade on e5
+	b, err := FromFEN("6k1/8/3P4/4p3/3P4/8/8/1Q2K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	extension := func(uci string, previous Move, pvNode bool) int {
+		move := findMove(t, b, uci)
+		after := b
+		_, err := after.makeMove(move)
+		assert.NoError(t, err)
+		return b.extension(move, &after, previous, pvNode)
+	}
+
+	assert.Equal(t, 1, extension("b1b8", Move{}, false), "check")
+	assert.Equal(t, 0, extension("b1b2", Move{}, true), "quiet move")
+	assert.Equal(t, 1, extension("d6d7", Move{}, true), "passed pawn push")
+	assert.Equal(t, 0, extension("d6d7", Move{}, false), "pushes only on the principal variation")
+
+	recapture := Move{Source: 44, Destination: 36, Piece: BlackPawn, CapturedPiece: WhitePawn}
+	assert.Equal(t, 1, extension("d4e5", recapture, true), "recapture")
+	assert.Equal(t, 0, extension("d4e5", Move{Source: 52, Destination: 44, Piece: BlackPawn, CapturedPiece: -1}, true), "plain capture")
+}
+
+func TestExtensionsSeeForcedLinesDeeper(t *testing.T) {
+	// Black mates in three with queen checks
+	b, err := FromFEN("2r3k1/p4p2/3Rp2p/1p2P1pK/8/1P4P1/P3Q2P/1q6 b - - 0 1")
+	assert.NoError(t, err)
+
+	options := DefaultSearchOptions()
+	options.Extensions = false
+	options.SingularExtensions = false
+	TranspositionTable.Clear()
+	result := b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, nil, 8, -3, -8, 1)
+	assert.False(t, IsMateScore(result.Score))
+
+	options = DefaultSearchOptions()
+	TranspositionTable.Clear()
+	result = b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, nil, 8, -3, -8, 1)
+	assert.Equal(t, 3, MateIn(result.Score))
+	assert.Equal(t, "b1g6", result.Move.UCI())
+	assert.Positive(t, result.Stats.Extensions)
+
+	// Without a budget nothing is extended
+	options.ExtensionBudget = 0
+	TranspositionTable.Clear()
+	result = b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, nil, 8, -3, -8, 1)
+	assert.Zero(t, result.Stats.Extensions)
+}
+
+func TestIsSingular(t *testing.T) {
+	s := newSearch(DefaultSearchOptions(), 8, -3, -8, 1)
+
+	// Only taking the queen keeps white from being a queen down
+	b, err := FromFEN("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	assert.NoError(t, err)
+	take := findMove(t, b, "d2d5")
+	TranspositionTable.Clear()
+	entry := TranspositionEntry{Depth: 6, Score: s.evaluate(&b) + 900, Flag: lowerBound, BestMove: take.Pack()}
+	assert.True(t, b.isSingular(entry, 6, 1, Move{}, s))
+	assert.Zero(t, s.excluded[1], "the move is put back")
+
+	// Many moves are about as good as e2e4 at the start
+	b = New()
+	e4 := findMove(t, b, "e2e4")
+	entry = TranspositionEntry{Depth: 6, Score: s.evaluate(&b), Flag: exact, BestMove: e4.Pack()}
+	assert.False(t, b.isSingular(entry, 6, 1, Move{}, s))
+}
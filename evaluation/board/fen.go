@@ -0,0 +1,227 @@
+package board
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"engine/evaluation/board/bitboards"
+)
+
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var backRanks = bitboards.BitBoard(0xFF000000000000FF)
+
+// FromFEN builds a board from a position in Forsyth-Edwards Notation.
+// The halfmove clock and fullmove number may be omitted, as in EPD strings.
+func FromFEN(fen string) (Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return Board{}, fmt.Errorf("invalid FEN %q: expected 4 or 6 fields, got %d", fen, len(fields))
+	}
+
+	var board Board
+
+	if err := board.placePieces(fields[0]); err != nil {
+		return Board{}, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	switch fields[1] {
+	case "w":
+		board.TurnBlack = false
+	case "b":
+		board.TurnBlack = true
+	default:
+		return Board{}, fmt.Errorf("invalid FEN %q: unknown side to move %q", fen, fields[1])
+	}
+
+	if err := board.setCastlingRights(fields[2]); err != nil {
+		return Board{}, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	if err := board.setEnPassantTarget(fields[3]); err != nil {
+		return Board{}, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	fullMove := 1
+	if len(fields) == 6 {
+		halfMoveClock, err := strconv.Atoi(fields[4])
+		if err != nil || halfMoveClock < 0 {
+			return Board{}, fmt.Errorf("invalid FEN %q: bad halfmove clock %q", fen, fields[4])
+		}
+		board.HalfMoveClock = halfMoveClock
+
+		fullMove, err = strconv.Atoi(fields[5])
+		if err != nil || fullMove < 1 {
+			return Board{}, fmt.Errorf("invalid FEN %q: bad fullmove number %q", fen, fields[5])
+		}
+	}
+
+	board.HalfTurn = 2 * (fullMove - 1)
+	if board.TurnBlack {
+		board.HalfTurn++
+	}
+
+	if err := board.validate(); err != nil {
+		return Board{}, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	board.Hash = board.computeHash()
+
+	return board, nil
+}
+
+func (board *Board) placePieces(placement string) error {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return fmt.Errorf("expected 8 ranks, got %d", len(ranks))
+	}
+
+	for i, rankString := range ranks {
+		rank := 7 - i
+		file := 0
+
+		for _, character := range rankString {
+			if character >= '1' && character <= '8' {
+				file += int(character - '0')
+				continue
+			}
+
+			piece := fenToPiece(character)
+			if piece == -1 {
+				return fmt.Errorf("unknown piece %q on rank %d", character, rank+1)
+			}
+			if file > 7 {
+				return fmt.Errorf("rank %d has more than 8 squares", rank+1)
+			}
+
+			*board.pieceBitboard(piece) |= bitboards.New(rank*8 + file)
+			file++
+		}
+
+		if file != 8 {
+			return fmt.Errorf("rank %d has %d squares", rank+1, file)
+		}
+	}
+
+	board.updateAggregateBitboards()
+
+	return nil
+}
+
+func (board *Board) setCastlingRights(rights string) error {
+	if rights == "-" {
+		return nil
+	}
+
+	for _, right := range rights {
+		switch right {
+		case 'K':
+			board.CastleWhiteKingside = true
+		case 'Q':
+			board.CastleWhiteQueenside = true
+		case 'k':
+			board.CastleBlackKingside = true
+		case 'q':
+			board.CastleBlackQueenside = true
+		default:
+			return fmt.Errorf("unknown castling right %q", right)
+		}
+	}
+
+	return nil
+}
+
+func (board *Board) setEnPassantTarget(square string) error {
+	if square == "-" {
+		return nil
+	}
+	if !isSquare(square) {
+		return fmt.Errorf("bad en passant square %q", square)
+	}
+
+	board.EnPassantTarget = bitboards.New(positionToIndex(square))
+
+	return nil
+}
+
+// validate rejects positions that cannot arise in a legal game.
+func (board Board) validate() error {
+	if board.WhiteKing.BitBoard().PopCount() != 1 {
+		return fmt.Errorf("white must have exactly one king, found %d", board.WhiteKing.BitBoard().PopCount())
+	}
+	if board.BlackKing.BitBoard().PopCount() != 1 {
+		return fmt.Errorf("black must have exactly one king, found %d", board.BlackKing.BitBoard().PopCount())
+	}
+
+	if (board.WhitePawns.BitBoard()|board.BlackPawns.BitBoard())&backRanks != 0 {
+		return fmt.Errorf("pawns cannot stand on the first or last rank")
+	}
+	if board.WhitePawns.BitBoard().PopCount() > 8 || board.BlackPawns.BitBoard().PopCount() > 8 {
+		return fmt.Errorf("a side cannot have more than 8 pawns")
+	}
+
+	if board.CastleWhiteKingside && (board.PieceAt(4) != WhiteKing || board.PieceAt(7) != WhiteRook) {
+		return fmt.Errorf("white cannot castle kingside without king on e1 and rook on h1")
+	}
+	if board.CastleWhiteQueenside && (board.PieceAt(4) != WhiteKing || board.PieceAt(0) != WhiteRook) {
+		return fmt.Errorf("white cannot castle queenside without king on e1 and rook on a1")
+	}
+	if board.CastleBlackKingside && (board.PieceAt(60) != BlackKing || board.PieceAt(63) != BlackRook) {
+		return fmt.Errorf("black cannot castle kingside without king on e8 and rook on h8")
+	}
+	if board.CastleBlackQueenside && (board.PieceAt(60) != BlackKing || board.PieceAt(56) != BlackRook) {
+		return fmt.Errorf("black cannot castle queenside without king on e8 and rook on a8")
+	}
+
+	if board.EnPassantTarget != 0 {
+		target := bits.TrailingZeros64(uint64(board.EnPassantTarget))
+		if board.TurnBlack && (target/8 != 2 || board.PieceAt(target+8) != WhitePawn || board.isOccupied(target)) {
+			return fmt.Errorf("en passant square %s does not follow a white double push", IndexToPosition(uint64(target)))
+		}
+		if !board.TurnBlack && (target/8 != 5 || board.PieceAt(target-8) != BlackPawn || board.isOccupied(target)) {
+			return fmt.Errorf("en passant square %s does not follow a black double push", IndexToPosition(uint64(target)))
+		}
+	}
+
+	if board.TurnBlack && board.isSquareAttacked(bits.TrailingZeros64(uint64(board.WhiteKing)), true) {
+		return fmt.Errorf("white is in check but it is black to move")
+	}
+	if !board.TurnBlack && board.isSquareAttacked(bits.TrailingZeros64(uint64(board.BlackKing)), false) {
+		return fmt.Errorf("black is in check but it is white to move")
+	}
+
+	return nil
+}
+
+func fenToPiece(character rune) int {
+	switch character {
+	case 'P':
+		return WhitePawn
+	case 'p':
+		return BlackPawn
+	case 'N':
+		return WhiteKnight
+	case 'n':
+		return BlackKnight
+	case 'B':
+		return WhiteBishop
+	case 'b':
+		return BlackBishop
+	case 'R':
+		return WhiteRook
+	case 'r':
+		return BlackRook
+	case 'Q':
+		return WhiteQueen
+	case 'q':
+		return BlackQueen
+	case 'K':
+		return WhiteKing
+	case 'k':
+		return BlackKing
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,61 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromFENStartingPosition(t *testing.T) {
+	b, err := FromFEN(StartingFEN)
+
+	assert.NoError(t, err)
+	assert.Equal(t, New(), b)
+	assert.Equal(t, StartingFEN, New().ToFEN())
+}
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"rnbqkbnr/pppp1ppp/8/8/3Pp3/8/PPP1PPPP/RNBQKBNR b Kq d3 0 3",
+		"8/8/8/4k3/8/8/4K3/8 b - - 47 112",
+	}
+
+	for _, fen := range fens {
+		b, err := FromFEN(fen)
+		if assert.NoError(t, err, fen) {
+			assert.Equal(t, fen, b.ToFEN())
+		}
+	}
+}
+
+func TestFromFENWithoutClocks(t *testing.T) {
+	b, err := FromFEN("2bq1rk1/pr3ppn/1p2p3/7P/2pP1B1P/2P5/PPQ2PB1/R3R1K1 w - -")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2bq1rk1/pr3ppn/1p2p3/7P/2pP1B1P/2P5/PPQ2PB1/R3R1K1 w - - 0 1", b.ToFEN())
+}
+
+func TestFromFENRejectsInvalidPositions(t *testing.T) {
+	fens := map[string]string{
+		"too few fields":         "8/8/8/8/8/8/8/8 w",
+		"short rank":             "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"unknown piece":          "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPX/RNBQKBNR w KQkq - 0 1",
+		"missing white king":     "4k3/8/8/8/8/8/8/8 w - - 0 1",
+		"two black kings":        "3kk3/8/8/8/8/8/8/4K3 w - - 0 1",
+		"pawn on back rank":      "P3k3/8/8/8/8/8/8/4K3 w - - 0 1",
+		"side not to move check": "4k3/8/8/8/8/8/8/4K2r b - - 0 1",
+		"castling without rook":  "4k3/8/8/8/8/8/8/4K3 w K - 0 1",
+		"impossible en passant":  "4k3/8/8/8/8/8/8/4K3 w - e6 0 1",
+		"unknown side":           "4k3/8/8/8/8/8/8/4K3 x - - 0 1",
+		"negative clock":         "4k3/8/8/8/8/8/8/4K3 w - - -1 1",
+	}
+
+	for name, fen := range fens {
+		_, err := FromFEN(fen)
+		assert.Error(t, err, name)
+	}
+}
@@ -1,6 +1,7 @@
 package board
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -14,32 +15,15 @@ func IndexToPosition(bitboard uint64) string {
 }
 
 func (board *Board) MakeHumanMove(move string) error {
-	parsedMove := board.UCItoMove(move)
-	if parsedMove.Source == parsedMove.Destination {
+	legalMove, ok := board.findLegalMove(move)
+	if !ok {
 		return errors.New("illegal move")
 	}
-
-	moves := board.AvailableWhiteMoves()
-
-	canMake := false
-	for _, move := range moves {
-		if parsedMove.Source == move.Source && parsedMove.Destination == move.Destination {
-			canMake = true
-			break
-		}
-	}
-	if !canMake {
-		return errors.New("illegal move")
-	}
-	// bestMove, eval := board.BestMove(12, OrderedMoves)
+	// bestMove, eval := board.BestMove(12, 8, -3, -8, 1)
 
 	// fmt.Print(PieceSymbols[board.PieceAt(int(bestMove.Source))], "(", IndexToPosition(uint64(bestMove.Source)), "): ", IndexToPosition(uint64(bestMove.Destination)), " ", eval)
 
-	if board.EnPassantTarget&bitboards.BitBoard(parsedMove.Destination) != 0 {
-		parsedMove.MoveType = EnPassant
-	}
-
-	_, err := board.makeMove(parsedMove)
+	_, err := board.makeMove(legalMove)
 	if err != nil {
 		return err
 	}
@@ -53,9 +37,41 @@ func (board *Board) MakeHumanMove(move string) error {
 	return nil
 }
 
+// MakeUCIMove plays a move given in UCI notation (e.g. "e2e4", "e7e8q") for the side to move.
+func (board *Board) MakeUCIMove(move string) error {
+	legalMove, ok := board.findLegalMove(move)
+	if !ok {
+		return errors.New("illegal move " + move)
+	}
+
+	_, err := board.makeMove(legalMove)
+	return err
+}
+
+// findLegalMove looks the UCI move up among the legal moves of the side to move.
+// A promotion without a piece letter is taken to be a queen promotion.
+func (board Board) findLegalMove(uci string) (Move, bool) {
+	for _, move := range board.LegalMoves() {
+		if move.UCI() == uci || move.UCI() == uci+"q" {
+			return move, true
+		}
+	}
+
+	return Move{}, false
+}
+
+func isSquare(square string) bool {
+	return len(square) == 2 && square[0] >= 'a' && square[0] <= 'h' && square[1] >= '1' && square[1] <= '8'
+}
+
 // Create a fixed number of workers
 
 func (board *Board) MakeMove(depth int) error {
+	return board.MakeTimedMove(depth, TimeControl{}, DefaultSearchOptions())
+}
+
+// MakeTimedMove searches up to maxDepth within the time budget of the clock and plays the best move found.
+func (board *Board) MakeTimedMove(maxDepth int, clock TimeControl, options SearchOptions) error {
 	// parsedMove := board.UCItoMove(move)
 
 	// f, err := os.Create("cpu.prof")
@@ -78,14 +94,19 @@ func (board *Board) MakeMove(depth int) error {
 	// 	log.Fatal("could not write memory profile: ", err)
 	// }
 
-	bestMove, eval := board.BestMove(depth, OrderedMoves, 8, -3, -8, 1)
+	var info func(SearchInfo)
+	if board.Debug {
+		info = func(info SearchInfo) { fmt.Println(info) }
+	}
+	result := board.Search(context.Background(), SearchLimits{TimeControl: clock, Depth: maxDepth, Options: &options}, info, 8, -3, -8, 1)
+	bestMove := result.Move
 
 	if board.Debug {
-		fmt.Println(PieceSymbols[board.PieceAt(int(bestMove.Source))], "(", IndexToPosition(uint64(bestMove.Destination)), ") material: ", eval.material, ", centre bonus: ", eval.centreBonus, ", mobility bonus: ", eval.mobilityBonus, ", pawn structure bonus: ", eval.pawnPenalties, ", knight placement bonus: ", eval.knightBonus, ", king safety bonus: ", eval.safety)
+		fmt.Println(PieceSymbols[board.PieceAt(int(bestMove.Source))], "(", IndexToPosition(uint64(bestMove.Destination)), ") score: ", result.Score, ", depth: ", result.Depth, ", pv: ", FormatPV(result.PV))
 	}
 
 	if bestMove.Source == bestMove.Destination {
-		fmt.Println("Resign")
+		return errors.New("no legal moves")
 	}
 
 	_, err := board.makeMove(bestMove)
@@ -152,31 +173,44 @@ func (board *Board) makeMove(move Move) (*MoveUndo, error) {
 		PreviousCastleWhiteQueenside: board.CastleWhiteQueenside,
 		PreviousCastleBlackKingside:  board.CastleBlackKingside,
 		PreviousCastleBlackQueenside: board.CastleBlackQueenside,
+		PreviousWhiteCastled:         board.WhiteCastled,
+		PreviousBlackCastled:         board.BlackCastled,
 		PreviousTurnBlack:            board.TurnBlack,
 		PreviousHalfTurn:             board.HalfTurn,
+		PreviousHalfMoveClock:        board.HalfMoveClock,
+		PreviousHash:                 board.Hash,
 		PreviousAggregateBitboards:   board.AggregateBitboards(), // Example, assume this captures all necessary board pieces
 	}
 
 	sourceBit := bitboards.New(move.Source)
 	destBit := bitboards.New(move.Destination)
 
+	// Take the old castling rights and en passant file out of the hash, the new ones are added back at the end
+	board.Hash ^= board.castlingHash() ^ enPassantHash(board.EnPassantTarget)
+
+	// Remove the captured piece first so capturing a piece of the same type keeps the mover
+	if move.CapturedPiece != -1 {
+		*board.pieceBitboard(move.CapturedPiece) &= ^destBit
+		board.Hash ^= zobristPieces[move.CapturedPiece][move.Destination]
+	}
+
 	*board.pieceBitboard(move.Piece) &= ^sourceBit
 	*board.pieceBitboard(move.Piece) |= destBit
+	board.Hash ^= zobristPieces[move.Piece][move.Source] ^ zobristPieces[move.Piece][move.Destination]
 
 	switch move.MoveType {
 	case Capture:
-		undo.CapturedPiece = move.CapturedPiece
-		*board.pieceBitboard(move.CapturedPiece) &= ^destBit
-		board.updateAggregateBitboards()
 	case EnPassant:
 		if move.Piece == WhitePawn {
-			capturedPawnBit := bitboards.New(move.Destination - 8) // or +8 depending on direction
-			*board.pieceBitboard(WhitePawn) &= ^capturedPawnBit    // or WhitePawn
+			capturedPawnBit := bitboards.New(move.Destination - 8) // the black pawn sits behind the target square
+			*board.pieceBitboard(BlackPawn) &= ^capturedPawnBit
+			board.Hash ^= zobristPieces[BlackPawn][move.Destination-8]
 		}
 
 		if move.Piece == BlackPawn {
-			capturedPawnBit := bitboards.New(move.Destination + 8) // or +8 depending on direction
-			*board.pieceBitboard(BlackPawn) &= ^capturedPawnBit    // or WhitePawn
+			capturedPawnBit := bitboards.New(move.Destination + 8) // the white pawn sits behind the target square
+			*board.pieceBitboard(WhitePawn) &= ^capturedPawnBit
+			board.Hash ^= zobristPieces[WhitePawn][move.Destination+8]
 		}
 	case CastleKingside:
 		if !board.CastleWhiteKingside && !board.CastleBlackKingside {
@@ -186,6 +220,7 @@ func (board *Board) makeMove(move Move) (*MoveUndo, error) {
 			board.WhiteCastled = true
 			*board.pieceBitboard(WhiteRook) &= ^bitboards.New(7) // original rook position for kingside
 			*board.pieceBitboard(WhiteRook) |= bitboards.New(5)  // new rook position for kingside
+			board.Hash ^= zobristPieces[WhiteRook][7] ^ zobristPieces[WhiteRook][5]
 			board.CastleWhiteKingside = false
 			board.CastleWhiteQueenside = false
 		}
@@ -193,6 +228,7 @@ func (board *Board) makeMove(move Move) (*MoveUndo, error) {
 			board.BlackCastled = true
 			*board.pieceBitboard(BlackRook) &= ^bitboards.New(63) // original rook position for kingside
 			*board.pieceBitboard(BlackRook) |= bitboards.New(61)  // new rook position for kingside
+			board.Hash ^= zobristPieces[BlackRook][63] ^ zobristPieces[BlackRook][61]
 			board.CastleBlackKingside = false
 			board.CastleBlackQueenside = false
 		}
@@ -201,22 +237,25 @@ func (board *Board) makeMove(move Move) (*MoveUndo, error) {
 			panic("?")
 		}
 		if move.Piece == WhiteKing && board.CastleWhiteQueenside {
-			board.BlackCastled = true
+			board.WhiteCastled = true
 			*board.pieceBitboard(WhiteRook) &= ^bitboards.New(0) // original rook position for kingside
 			*board.pieceBitboard(WhiteRook) |= bitboards.New(3)  // new rook position for kingside
+			board.Hash ^= zobristPieces[WhiteRook][0] ^ zobristPieces[WhiteRook][3]
 			board.CastleWhiteKingside = false
 			board.CastleWhiteQueenside = false
 		}
 		if move.Piece == BlackKing && board.CastleBlackQueenside {
-			board.WhiteCastled = true
+			board.BlackCastled = true
 			*board.pieceBitboard(BlackRook) &= ^bitboards.New(56) // original rook position for kingside
 			*board.pieceBitboard(BlackRook) |= bitboards.New(59)  // new rook position for kingside
+			board.Hash ^= zobristPieces[BlackRook][56] ^ zobristPieces[BlackRook][59]
 			board.CastleBlackKingside = false
 			board.CastleBlackQueenside = false
 		}
 	case Promotion:
 		*board.pieceBitboard(move.Piece) &= ^destBit         // Remove pawn from destination
 		*board.pieceBitboard(move.PromotionPiece) |= destBit // Add queen to destination
+		board.Hash ^= zobristPieces[move.Piece][move.Destination] ^ zobristPieces[move.PromotionPiece][move.Destination]
 	case NormalMove:
 	default:
 		panic("")
@@ -232,25 +271,99 @@ func (board *Board) makeMove(move Move) (*MoveUndo, error) {
 		board.CastleWhiteQueenside = false
 	}
 
-	if move.Source == 7 {
+	// A rook leaving or being captured on its corner loses that side's castling right
+	if move.Source == 7 || move.Destination == 7 {
 		board.CastleWhiteKingside = false
 	}
 
-	if move.Source == 0 {
+	if move.Source == 0 || move.Destination == 0 {
 		board.CastleWhiteQueenside = false
 	}
 
-	if move.Source == 63 {
+	if move.Source == 63 || move.Destination == 63 {
 		board.CastleBlackKingside = false
 	}
 
-	if move.Source == 55 {
+	if move.Source == 56 || move.Destination == 56 {
 		board.CastleBlackQueenside = false
 	}
 
 	board.updateAggregateBitboards()
+
+	if move.Piece == WhitePawn && move.Destination-move.Source == 16 {
+		board.EnPassantTarget = bitboards.New(move.Source + 8)
+	}
+
+	if move.Piece == BlackPawn && move.Source-move.Destination == 16 {
+		board.EnPassantTarget = bitboards.New(move.Source - 8)
+	}
+
+	// Captures and pawn moves cannot be taken back, so no earlier position can come up again
+	if move.CapturedPiece != -1 || move.MoveType == EnPassant || move.Piece == WhitePawn || move.Piece == BlackPawn {
+		board.HalfMoveClock = 0
+	} else {
+		board.HalfMoveClock++
+	}
+
 	board.TurnBlack = !board.TurnBlack
 	board.HalfTurn++
+	board.History = append(board.History, undo.PreviousHash)
+	board.Hash ^= board.castlingHash() ^ enPassantHash(board.EnPassantTarget) ^ zobristBlackTurn
 
 	return &undo, nil
 }
+
+// NullMoveUndo holds what MakeNullMove changed besides the turn.
+type NullMoveUndo struct {
+	PreviousEnPassantTarget bitboards.BitBoard
+	PreviousHalfMoveClock   int
+	PreviousHash            uint64
+}
+
+// MakeNullMove passes the turn to the other side without moving a piece. The en passant square is lost.
+// The halfmove clock starts again, so repetitions are not looked for across the null move.
+func (board *Board) MakeNullMove() NullMoveUndo {
+	undo := NullMoveUndo{PreviousEnPassantTarget: board.EnPassantTarget, PreviousHalfMoveClock: board.HalfMoveClock, PreviousHash: board.Hash}
+
+	board.History = append(board.History, board.Hash)
+	board.Hash ^= enPassantHash(board.EnPassantTarget) ^ zobristBlackTurn
+	board.EnPassantTarget = 0
+	board.HalfMoveClock = 0
+	board.TurnBlack = !board.TurnBlack
+	board.HalfTurn++
+
+	return undo
+}
+
+// UndoNullMove takes back a MakeNullMove.
+func (board *Board) UndoNullMove(undo NullMoveUndo) {
+	board.EnPassantTarget = undo.PreviousEnPassantTarget
+	board.HalfMoveClock = undo.PreviousHalfMoveClock
+	board.Hash = undo.PreviousHash
+	board.History = board.History[:len(board.History)-1]
+	board.TurnBlack = !board.TurnBlack
+	board.HalfTurn--
+}
+
+// repetitions counts the earlier occurrences of the position, looking back no further than the last capture
+// or pawn move. Only every other position can match, the others have the other side to move.
+func (board *Board) repetitions() int {
+	count := 0
+	for i := len(board.History) - 2; i >= 0 && i >= len(board.History)-board.HalfMoveClock; i -= 2 {
+		if board.History[i] == board.Hash {
+			count++
+		}
+	}
+
+	return count
+}
+
+// IsThreefoldRepetition reports whether the position has come up for the third time, which is a draw.
+func (board *Board) IsThreefoldRepetition() bool {
+	return board.repetitions() >= 2
+}
+
+// IsFiftyMoveDraw reports whether fifty moves by each side went by without a capture or pawn move.
+func (board *Board) IsFiftyMoveDraw() bool {
+	return board.HalfMoveClock >= 100
+}
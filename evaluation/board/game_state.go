@@ -0,0 +1,65 @@
+package board
+
+// GameState tells whether the game goes on and, once it is over, why.
+type GameState int
+
+const (
+	InProgress GameState = iota
+	Checkmate
+	Stalemate
+	ThreefoldRepetition
+	FiftyMoveRule
+	InsufficientMaterial
+)
+
+func (state GameState) String() string {
+	switch state {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	case FiftyMoveRule:
+		return "fifty-move rule"
+	case InsufficientMaterial:
+		return "insufficient material"
+	}
+	return "in progress"
+}
+
+// GameState returns how the game stands for the side to move. A mate delivered on the
+// move that reaches the fifty-move limit still counts as a mate.
+func (board *Board) GameState() GameState {
+	if len(board.LegalMoves()) == 0 {
+		if board.inCheck() {
+			return Checkmate
+		}
+		return Stalemate
+	}
+
+	switch {
+	case board.isDeadDraw():
+		return InsufficientMaterial
+	case board.IsThreefoldRepetition():
+		return ThreefoldRepetition
+	case board.IsFiftyMoveDraw():
+		return FiftyMoveRule
+	}
+
+	return InProgress
+}
+
+// Result returns the result of the game in PGN notation: "1-0", "0-1" or "1/2-1/2", and "*" while it goes on.
+func (board *Board) Result() string {
+	switch board.GameState() {
+	case InProgress:
+		return "*"
+	case Checkmate:
+		if board.TurnBlack {
+			return "1-0"
+		}
+		return "0-1"
+	}
+	return "1/2-1/2"
+}
@@ -0,0 +1,104 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreefoldRepetition(t *testing.T) {
+	b := New()
+
+	for i, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"} {
+		assert.False(t, b.IsThreefoldRepetition(), "after %d moves", i)
+		assert.NoError(t, b.MakeUCIMove(move))
+	}
+
+	// The starting position came up for the third time
+	assert.True(t, b.IsThreefoldRepetition())
+	assert.Equal(t, 8, b.HalfMoveClock)
+	assert.Len(t, b.History, 8)
+}
+
+func TestIrreversibleMovesResetTheClock(t *testing.T) {
+	b := New()
+	assert.NoError(t, b.MakeUCIMove("g1f3"))
+	assert.Equal(t, 1, b.HalfMoveClock)
+
+	undo, err := b.makeMove(findMove(t, b, "e7e5"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, b.HalfMoveClock)
+
+	b.UndoMove(undo)
+	assert.Equal(t, 1, b.HalfMoveClock)
+	assert.Len(t, b.History, 1)
+
+	// A position from before a pawn move cannot come back
+	for _, move := range []string{"e7e5", "f3g1", "e8e7", "g1f3", "e7e8", "f3g1", "e8e7", "g1f3", "e7e8"} {
+		assert.NoError(t, b.MakeUCIMove(move))
+	}
+	assert.False(t, b.IsThreefoldRepetition())
+}
+
+func TestFiftyMoveDraw(t *testing.T) {
+	b, err := FromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	assert.NoError(t, err)
+	assert.False(t, b.IsFiftyMoveDraw())
+
+	assert.NoError(t, b.MakeUCIMove("a1a2"))
+	assert.True(t, b.IsFiftyMoveDraw())
+}
+
+func TestSearchScoresRepetitionAsDraw(t *testing.T) {
+	b := New()
+	for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+		assert.NoError(t, b.MakeUCIMove(move))
+	}
+
+	s := newSearch(DefaultSearchOptions(), 8, -3, -8, 1)
+	assert.Equal(t, int32(0), b.negamax(3, -Infinity, Infinity, 1, Move{}, s, new([]Move)))
+
+	// A rook up is worth nothing when every move reaches the fifty-move limit
+	up, err := FromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	assert.NoError(t, err)
+	result := up.IterativeDeepening(2, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.Equal(t, int32(0), result.Score)
+
+	// Unless the move reaching the limit mates
+	mate, err := FromFEN("6k1/5ppp/8/8/8/8/8/R3K3 w - - 99 80")
+	assert.NoError(t, err)
+	result = mate.IterativeDeepening(2, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.Equal(t, "a1a8", result.Move.UCI())
+	assert.Equal(t, MateScore-1, result.Score)
+}
+
+func TestGameState(t *testing.T) {
+	for _, test := range []struct {
+		fen    string
+		state  GameState
+		result string
+	}{
+		{StartingFEN, InProgress, "*"},
+		{"R5k1/5ppp/8/8/8/8/8/4K3 b - - 0 1", Checkmate, "1-0"},
+		{"4k3/8/8/8/8/8/5PPP/3r2K1 w - - 0 1", Checkmate, "0-1"},
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Stalemate, "1/2-1/2"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 100 80", FiftyMoveRule, "1/2-1/2"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", InsufficientMaterial, "1/2-1/2"},
+		{"4k3/8/8/8/8/8/8/2N1K3 w - - 0 1", InsufficientMaterial, "1/2-1/2"},
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", InsufficientMaterial, "1/2-1/2"},
+		{"4k1b1/8/8/8/8/8/8/2B1K3 w - - 0 1", InProgress, "*"},
+		{"4k3/8/8/8/8/8/8/1NN1K3 w - - 0 1", InProgress, "*"},
+	} {
+		b, err := FromFEN(test.fen)
+		assert.NoError(t, err)
+		assert.Equal(t, test.state, b.GameState(), test.fen)
+		assert.Equal(t, test.result, b.Result(), test.fen)
+	}
+
+	// Mate on the move that reaches the fifty-move limit
+	b, err := FromFEN("6k1/5ppp/8/8/8/8/8/R3K3 w - - 99 80")
+	assert.NoError(t, err)
+	assert.NoError(t, b.MakeUCIMove("a1a8"))
+	assert.Equal(t, Checkmate, b.GameState())
+	assert.Equal(t, "checkmate", b.GameState().String())
+}
@@ -0,0 +1,261 @@
+package board
+
+import (
+	"math/bits"
+
+	"engine/evaluation/board/bitboards"
+)
+
+// sidePieces groups the bitboards of one colour.
+type sidePieces struct {
+	pawns   bitboards.BitBoard
+	knights bitboards.BitBoard
+	bishops bitboards.BitBoard
+	rooks   bitboards.BitBoard
+	queens  bitboards.BitBoard
+	king    bitboards.BitBoard
+	all     bitboards.BitBoard
+
+	pawn, knight, bishop, rook, queen, kingPiece int // Piece constants of this colour
+}
+
+func (board Board) side(black bool) sidePieces {
+	if black {
+		return sidePieces{
+			pawns: board.BlackPawns.BitBoard(), knights: board.BlackKnights.BitBoard(), bishops: board.BlackBishops.BitBoard(),
+			rooks: board.BlackRooks.BitBoard(), queens: board.BlackQueens.BitBoard(), king: board.BlackKing.BitBoard(), all: board.BlackPieces,
+			pawn: BlackPawn, knight: BlackKnight, bishop: BlackBishop, rook: BlackRook, queen: BlackQueen, kingPiece: BlackKing,
+		}
+	}
+
+	return sidePieces{
+		pawns: board.WhitePawns.BitBoard(), knights: board.WhiteKnights.BitBoard(), bishops: board.WhiteBishops.BitBoard(),
+		rooks: board.WhiteRooks.BitBoard(), queens: board.WhiteQueens.BitBoard(), king: board.WhiteKing.BitBoard(), all: board.WhitePieces,
+		pawn: WhitePawn, knight: WhiteKnight, bishop: WhiteBishop, rook: WhiteRook, queen: WhiteQueen, kingPiece: WhiteKing,
+	}
+}
+
+// attackersTo returns the pieces of both colours attacking the square, with sliders blocked by the given occupancy.
+func (board Board) attackersTo(square int, occupied bitboards.BitBoard) bitboards.BitBoard {
+	target := bitboards.New(square)
+
+	diagonal := board.WhiteBishops.BitBoard() | board.BlackBishops.BitBoard() | board.WhiteQueens.BitBoard() | board.BlackQueens.BitBoard()
+	straight := board.WhiteRooks.BitBoard() | board.BlackRooks.BitBoard() | board.WhiteQueens.BitBoard() | board.BlackQueens.BitBoard()
+
+	// A pawn attacks the target from the squares a pawn of the other colour would attack
+	attackers := bitboards.BlackPawnBitboard(target).Attacks() & board.WhitePawns.BitBoard()
+	attackers |= bitboards.WhitePawnBitboard(target).Attacks() & board.BlackPawns.BitBoard()
+	attackers |= bitboards.KnightBitboard(target).Attacks(board.WhiteKnights.BitBoard() | board.BlackKnights.BitBoard())
+	attackers |= bitboards.KingBitboard(target).Attacks(board.WhiteKing.BitBoard() | board.BlackKing.BitBoard())
+	attackers |= bitboards.BishopAttacks(square, occupied) & diagonal
+	attackers |= bitboards.RookAttacks(square, occupied) & straight
+
+	return attackers & occupied
+}
+
+// squaresBetween returns the squares strictly between two squares sharing a rank, file or diagonal, or 0 otherwise.
+func squaresBetween(a, b int) bitboards.BitBoard {
+	aBit, bBit := bitboards.New(a), bitboards.New(b)
+
+	if fromA := bitboards.RookAttacks(a, bBit); fromA&bBit != 0 {
+		return fromA & bitboards.RookAttacks(b, aBit)
+	}
+	if fromA := bitboards.BishopAttacks(a, bBit); fromA&bBit != 0 {
+		return fromA & bitboards.BishopAttacks(b, aBit)
+	}
+
+	return 0
+}
+
+// pins returns the pieces pinned to the king and, for each of them, the ray it may still move along
+// (the squares up to and including the pinning piece).
+func (board Board) pins(kingSquare int, us, them sidePieces) (bitboards.BitBoard, [64]bitboards.BitBoard) {
+	var pinned bitboards.BitBoard
+	var pinRays [64]bitboards.BitBoard
+
+	// Look through our own pieces to find enemy sliders lined up with the king
+	snipers := bitboards.RookAttacks(kingSquare, them.all)&(them.rooks|them.queens) |
+		bitboards.BishopAttacks(kingSquare, them.all)&(them.bishops|them.queens)
+
+	for snipers != 0 {
+		sniper := int(snipers.PopLSB())
+		between := squaresBetween(kingSquare, sniper)
+		blockers := between & board.OccupiedSquares
+
+		if blockers.PopCount() == 1 && blockers&us.all != 0 {
+			pinned |= blockers
+			pinRays[blockers.Lsb()] = between | bitboards.New(sniper)
+		}
+	}
+
+	return pinned, pinRays
+}
+
+// generateLegalMoves produces every legal move for the given colour. Checkers and pins are computed once,
+// so only king moves, en passant captures and castling need to look at attacked squares.
+func (board Board) generateLegalMoves(black bool) []Move {
+	us, them := board.side(black), board.side(!black)
+	if us.king == 0 {
+		return nil
+	}
+
+	moves := make([]Move, 0, 48)
+	kingSquare := bits.TrailingZeros64(uint64(us.king))
+	checkers := board.attackersTo(kingSquare, board.OccupiedSquares) & them.all
+
+	// The king is lifted off the board so it cannot step back along the line of a checking slider
+	withoutKing := board.OccupiedSquares &^ us.king
+	kingTargets := bitboards.KingBitboard(us.king).Moves(board.EmptySquares, them.all)
+	for kingTargets != 0 {
+		to := int(kingTargets.PopLSB())
+		if board.attackersTo(to, withoutKing)&them.all == 0 {
+			moves = append(moves, board.newMove(kingSquare, to, us.kingPiece))
+		}
+	}
+
+	// In double check only the king can move
+	if checkers.PopCount() > 1 {
+		return moves
+	}
+
+	// Outside check any square will do, in check a move has to capture the checker or block it
+	targetMask := ^bitboards.BitBoard(0)
+	if checkers != 0 {
+		targetMask = checkers | squaresBetween(kingSquare, int(checkers.Lsb()))
+	} else {
+		moves = board.appendCastles(moves, black)
+	}
+
+	pinned, pinRays := board.pins(kingSquare, us, them)
+	allowed := func(from uint8, targets bitboards.BitBoard) bitboards.BitBoard {
+		targets &= targetMask
+		if pinned&bitboards.New(int(from)) != 0 {
+			targets &= pinRays[from]
+		}
+		return targets
+	}
+
+	knights := us.knights
+	for knights != 0 {
+		from := knights.PopLSB()
+
+		movesList := allowed(from, bitboards.KnightBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, them.all))
+		for movesList != 0 {
+			to := movesList.PopLSB()
+			moves = append(moves, board.newMove(int(from), int(to), us.knight))
+		}
+	}
+
+	bishops := us.bishops
+	for bishops != 0 {
+		from := bishops.PopLSB()
+
+		movesList := allowed(from, bitboards.BishopAttacks(int(from), board.OccupiedSquares)&^us.all)
+		for movesList != 0 {
+			to := movesList.PopLSB()
+			moves = append(moves, board.newMove(int(from), int(to), us.bishop))
+		}
+	}
+
+	rooks := us.rooks
+	for rooks != 0 {
+		from := rooks.PopLSB()
+
+		movesList := allowed(from, bitboards.RookAttacks(int(from), board.OccupiedSquares)&^us.all)
+		for movesList != 0 {
+			to := movesList.PopLSB()
+			moves = append(moves, board.newMove(int(from), int(to), us.rook))
+		}
+	}
+
+	queens := us.queens
+	for queens != 0 {
+		from := queens.PopLSB()
+
+		movesList := allowed(from, bitboards.QueenAttacks(int(from), board.OccupiedSquares)&^us.all)
+		for movesList != 0 {
+			to := movesList.PopLSB()
+			moves = append(moves, board.newMove(int(from), int(to), us.queen))
+		}
+	}
+
+	pawns := us.pawns
+	for pawns != 0 {
+		from := pawns.PopLSB()
+
+		var movesList bitboards.BitBoard
+		if black {
+			movesList = bitboards.BlackPawnBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, them.all, 0)
+		} else {
+			movesList = bitboards.WhitePawnBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, them.all, 0)
+		}
+
+		movesList = allowed(from, movesList)
+		for movesList != 0 {
+			to := movesList.PopLSB()
+			moves = board.appendPawnMoves(moves, int(from), int(to), us.pawn)
+		}
+	}
+
+	return board.appendEnPassant(moves, black, kingSquare)
+}
+
+// appendEnPassant adds the legal en passant captures. Removing two pawns from one rank can expose the king,
+// so each capture is played out and tested instead of relying on the pin rays.
+func (board Board) appendEnPassant(moves []Move, black bool, kingSquare int) []Move {
+	if board.EnPassantTarget == 0 {
+		return moves
+	}
+
+	us := board.side(black)
+
+	// The target square has to belong to a double push of the other colour
+	to := int(board.EnPassantTarget.Lsb())
+	if (black && to/8 != 2) || (!black && to/8 != 5) {
+		return moves
+	}
+
+	var capturers bitboards.BitBoard
+	if black {
+		capturers = bitboards.WhitePawnBitboard(board.EnPassantTarget).Attacks() & us.pawns
+	} else {
+		capturers = bitboards.BlackPawnBitboard(board.EnPassantTarget).Attacks() & us.pawns
+	}
+
+	for capturers != 0 {
+		from := int(capturers.PopLSB())
+		move := Move{Source: from, Destination: to, Piece: us.pawn, CapturedPiece: -1, MoveType: EnPassant}
+
+		tmpBoard := board
+		if _, err := tmpBoard.makeMove(move); err != nil {
+			panic(err)
+		}
+		if tmpBoard.attackersTo(kingSquare, tmpBoard.OccupiedSquares)&tmpBoard.side(!black).all == 0 {
+			moves = append(moves, move)
+		}
+	}
+
+	return moves
+}
+
+// appendCastles adds the castling moves. The king may not castle out of, through or into check.
+func (board Board) appendCastles(moves []Move, black bool) []Move {
+	if black {
+		if board.CastleBlackQueenside && !board.isOccupied(57) && !board.isOccupied(58) && !board.isOccupied(59) && board.PieceAt(56) == BlackRook && !board.isSquareAttacked(59, false) && !board.isSquareAttacked(58, false) {
+			moves = append(moves, Move{Source: 60, Destination: 58, MoveType: CastleQueenside, Piece: BlackKing, CapturedPiece: -1})
+		}
+		if board.CastleBlackKingside && !board.isOccupied(61) && !board.isOccupied(62) && board.PieceAt(63) == BlackRook && !board.isSquareAttacked(61, false) && !board.isSquareAttacked(62, false) {
+			moves = append(moves, Move{Source: 60, Destination: 62, MoveType: CastleKingside, Piece: BlackKing, CapturedPiece: -1})
+		}
+		return moves
+	}
+
+	if board.CastleWhiteQueenside && !board.isOccupied(1) && !board.isOccupied(2) && !board.isOccupied(3) && board.PieceAt(0) == WhiteRook && !board.isSquareAttacked(3, true) && !board.isSquareAttacked(2, true) {
+		moves = append(moves, Move{Source: 4, Destination: 2, MoveType: CastleQueenside, Piece: WhiteKing, CapturedPiece: -1})
+	}
+	if board.CastleWhiteKingside && !board.isOccupied(5) && !board.isOccupied(6) && board.PieceAt(7) == WhiteRook && !board.isSquareAttacked(5, true) && !board.isSquareAttacked(6, true) {
+		moves = append(moves, Move{Source: 4, Destination: 6, MoveType: CastleKingside, Piece: WhiteKing, CapturedPiece: -1})
+	}
+
+	return moves
+}
@@ -0,0 +1,72 @@
+package board
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func legalMovesUCI(t *testing.T, fen string) []string {
+	b, err := FromFEN(fen)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	var moves []string
+	for _, move := range b.LegalMoves() {
+		moves = append(moves, move.UCI())
+	}
+	sort.Strings(moves)
+
+	return moves
+}
+
+func TestLegalMovesDoubleCheckOnlyMovesKing(t *testing.T) {
+	// Rook on e8 and knight on d3 both give check, the rook on a3 could otherwise capture the knight
+	moves := legalMovesUCI(t, "4r2k/8/8/8/8/R2n4/8/4K3 w - - 0 1")
+
+	assert.Equal(t, []string{"e1d1", "e1d2", "e1f1"}, moves)
+}
+
+func TestLegalMovesPinnedPieceStaysOnRay(t *testing.T) {
+	// The bishop on d2 is pinned by the bishop on b4 and may only capture it or stay on the diagonal
+	moves := legalMovesUCI(t, "7k/8/8/8/1b6/8/3B4/4K3 w - - 0 1")
+
+	assert.Contains(t, moves, "d2c3")
+	assert.Contains(t, moves, "d2b4")
+	assert.NotContains(t, moves, "d2e3")
+	assert.NotContains(t, moves, "d2c1")
+}
+
+func TestLegalMovesEnPassantDiscoveredCheck(t *testing.T) {
+	// Capturing en passant would remove both pawns from the fifth rank and expose the king to the rook
+	moves := legalMovesUCI(t, "8/8/8/KPp4r/8/8/8/7k w - c6 0 1")
+
+	assert.NotContains(t, moves, "b5c6")
+	assert.Contains(t, moves, "b5b6")
+}
+
+func TestLegalMovesEnPassantCapturesChecker(t *testing.T) {
+	// The pawn that just double pushed gives check and can be taken en passant
+	moves := legalMovesUCI(t, "8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1")
+
+	assert.Contains(t, moves, "e4d3")
+}
+
+func TestLegalMovesNoCastlingThroughAttack(t *testing.T) {
+	// The bishop on c4 covers f1, so only queenside castling is allowed
+	moves := legalMovesUCI(t, "4k3/8/8/8/2b5/8/8/R3K2R w KQ - 0 1")
+
+	assert.Contains(t, moves, "e1c1")
+	assert.NotContains(t, moves, "e1g1")
+}
+
+func TestLegalMovesBlackToMove(t *testing.T) {
+	b := New()
+	assert.NoError(t, b.MakeUCIMove("e2e4"))
+
+	assert.Len(t, b.LegalMoves(), 20)
+	assert.Error(t, b.MakeHumanMove("d2d4"))
+	assert.NoError(t, b.MakeUCIMove("e7e5"))
+}
@@ -0,0 +1,12 @@
+package board
+
+import (
+	"testing"
+
+	"engine/evaluation/board/bitboards"
+)
+
+func TestMain(m *testing.M) {
+	bitboards.InitBitboards()
+	m.Run()
+}
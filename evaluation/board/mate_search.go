@@ -0,0 +1,166 @@
+package board
+
+import (
+	"context"
+	"slices"
+)
+
+// MateResult is the outcome of SolveMate.
+type MateResult struct {
+	Moves int    // Full moves until mate, 0 when there is no mate within the limit
+	PV    []Move // The mating line, the defender putting up the longest resistance
+	Nodes uint64
+}
+
+// mateSearch holds the state of one SolveMate call. Positions are remembered by hash alone, the
+// path that led to them is ignored: a forced mate never needs a repetition.
+type mateSearch struct {
+	stop    <-chan struct{}
+	nodes   uint64
+	aborted bool
+	proven  map[uint64]int // Fewest moves a mate was proven in from the position, attacker to move
+	refuted map[uint64]int // Most moves the attacker was shown not to mate in
+}
+
+// SolveMate proves or refutes a forced mate for the side to move in at most maxMoves moves. The attacker
+// only plays checks, those leaving the defender the fewest replies first, and the defender tries every
+// evasion; the limit is raised one move at a time so the shortest mate is found. It returns the context's
+// error when the context is done before the search is.
+func (board *Board) SolveMate(ctx context.Context, maxMoves int) (MateResult, error) {
+	m := &mateSearch{stop: ctx.Done(), proven: map[uint64]int{}, refuted: map[uint64]int{}}
+
+	for moves := 1; moves <= maxMoves; moves++ {
+		if err := ctx.Err(); err != nil {
+			return MateResult{Nodes: m.nodes}, err
+		}
+
+		mated := board.mateIn(moves, m)
+		if m.aborted {
+			return MateResult{Nodes: m.nodes}, ctx.Err()
+		}
+		if mated {
+			pv := board.matingLine(moves, m)
+			if m.aborted {
+				return MateResult{Nodes: m.nodes}, ctx.Err()
+			}
+			return MateResult{Moves: moves, PV: pv, Nodes: m.nodes}, nil
+		}
+	}
+
+	return MateResult{Nodes: m.nodes}, nil
+}
+
+// mateIn reports whether the side to move mates in at most moves moves whatever the defence.
+func (board *Board) mateIn(moves int, m *mateSearch) bool {
+	if m.shouldStop() {
+		return false
+	}
+	if proven, ok := m.proven[board.Hash]; ok && proven <= moves {
+		return true
+	}
+	if refuted, ok := m.refuted[board.Hash]; ok && refuted >= moves {
+		return false
+	}
+
+	for _, check := range board.checks() {
+		if check.replies == 0 || moves > 1 && check.after.mateAfterEveryEvasion(moves-1, m) {
+			m.proven[board.Hash] = moves
+			return true
+		}
+	}
+
+	if !m.aborted {
+		m.refuted[board.Hash] = moves
+	}
+	return false
+}
+
+// defends reports whether the side to move, in check, has an evasion after which the attacker does not
+// mate in moves moves. A search cut short leaves every evasion standing.
+func (board *Board) defends(moves int, m *mateSearch) bool {
+	for _, evasion := range board.LegalMoves() {
+		after := *board
+		if _, err := after.makeMove(evasion); err != nil {
+			panic(err)
+		}
+		if !after.mateIn(moves, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// mateCheck is a checking move of the attacker with the position it leads to.
+type mateCheck struct {
+	move    Move
+	after   Board
+	replies int // Legal moves left to the defender, 0 for mate
+}
+
+// checks returns the checking moves of the side to move, the ones leaving the fewest replies first.
+func (board *Board) checks() []mateCheck {
+	var checks []mateCheck
+	for _, move := range board.LegalMoves() {
+		after := *board
+		if _, err := after.makeMove(move); err != nil {
+			panic(err)
+		}
+		if after.inCheck() {
+			checks = append(checks, mateCheck{move: move, after: after, replies: len(after.LegalMoves())})
+		}
+	}
+
+	slices.SortStableFunc(checks, func(a, b mateCheck) int { return a.replies - b.replies })
+	return checks
+}
+
+// matingLine returns the moves of a mate in moves moves, which mateIn has proven: the fastest mate for the
+// attacker against the evasion that holds out longest.
+func (board *Board) matingLine(moves int, m *mateSearch) []Move {
+	for _, check := range board.checks() {
+		if check.replies == 0 {
+			return []Move{check.move}
+		}
+		if moves == 1 || !check.after.mateAfterEveryEvasion(moves-1, m) {
+			continue
+		}
+
+		// The defender picks the evasion that takes the most moves to mate
+		var longest []Move
+		for _, evasion := range check.after.LegalMoves() {
+			after := check.after
+			if _, err := after.makeMove(evasion); err != nil {
+				panic(err)
+			}
+			for needed := 1; needed < moves; needed++ {
+				if after.mateIn(needed, m) {
+					if line := append([]Move{evasion}, after.matingLine(needed, m)...); len(line) > len(longest) {
+						longest = line
+					}
+					break
+				}
+			}
+		}
+		return append([]Move{check.move}, longest...)
+	}
+
+	return nil
+}
+
+// mateAfterEveryEvasion reports whether the attacker mates in moves moves after every evasion of the side to move.
+func (board *Board) mateAfterEveryEvasion(moves int, m *mateSearch) bool {
+	return !board.defends(moves, m) && !m.aborted
+}
+
+// shouldStop counts the node and reports whether the context is done, which is only looked at every so often.
+func (m *mateSearch) shouldStop() bool {
+	m.nodes++
+	if m.nodes%1024 == 0 {
+		select {
+		case <-m.stop:
+			m.aborted = true
+		default:
+		}
+	}
+	return m.aborted
+}
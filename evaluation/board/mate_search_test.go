@@ -0,0 +1,60 @@
+package board
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolveMate(t *testing.T) {
+	for _, test := range []struct {
+		fen   string
+		moves int
+		pv    string
+	}{
+		{"6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", 1, "a1a8"},
+		{"2r3k1/p4p2/3Rp2p/1p2P1pK/8/1P4P1/P3Q2P/1q6 b - - 0 1", 3, ""},
+		{"6rk/6pp/8/6N1/2Q5/8/8/6K1 w - - 0 1", 1, "g5f7"},
+	} {
+		b, err := FromFEN(test.fen)
+		assert.NoError(t, err)
+
+		result, err := b.SolveMate(context.Background(), 4)
+		assert.NoError(t, err)
+		assert.Equal(t, test.moves, result.Moves, test.fen)
+		assert.Len(t, result.PV, 2*test.moves-1, test.fen)
+		if test.pv != "" {
+			assert.Equal(t, test.pv, FormatPV(result.PV), test.fen)
+		}
+
+		// The line ends in mate
+		for _, move := range result.PV {
+			_, err := b.makeMove(move)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, Checkmate, b.GameState(), test.fen)
+	}
+}
+
+func TestSolveMateRefutes(t *testing.T) {
+	// Black makes luft in time, and the start position has no mate at all
+	for _, fen := range []string{"6k1/5ppp/8/8/8/8/8/R3K3 b - - 0 1", StartingFEN} {
+		b, err := FromFEN(fen)
+		assert.NoError(t, err)
+
+		result, err := b.SolveMate(context.Background(), 3)
+		assert.NoError(t, err)
+		assert.Zero(t, result.Moves)
+		assert.Empty(t, result.PV)
+	}
+}
+
+func TestSolveMateStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b := New()
+	_, err := b.SolveMate(ctx, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+}
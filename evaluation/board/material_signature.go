@@ -0,0 +1,123 @@
+package board
+
+import (
+	"math/bits"
+
+	"engine/evaluation/board/bitboards"
+)
+
+// The evaluation is scaled by drawScale/scaleNormal in endings that material alone does not win.
+const (
+	scaleNormal     int32 = 64
+	scaleLikelyDraw int32 = 4
+	scaleDraw       int32 = 0
+)
+
+// lightSquares are the squares of the colour of h1.
+const lightSquares = bitboards.BitBoard(0x55AA55AA55AA55AA)
+
+// materialSignature counts the pieces of one side.
+type materialSignature struct {
+	pawns, knights, bishops, rooks, queens int
+}
+
+func (board *Board) materialSignature(black bool) materialSignature {
+	side := board.side(black)
+	return materialSignature{
+		pawns:   bits.OnesCount64(uint64(side.pawns)),
+		knights: bits.OnesCount64(uint64(side.knights)),
+		bishops: bits.OnesCount64(uint64(side.bishops)),
+		rooks:   bits.OnesCount64(uint64(side.rooks)),
+		queens:  bits.OnesCount64(uint64(side.queens)),
+	}
+}
+
+// bareKing reports whether the side has nothing but its king.
+func (signature materialSignature) bareKing() bool {
+	return signature == materialSignature{}
+}
+
+// minorsOnly reports whether the side has no pawns, rooks or queens.
+func (signature materialSignature) minorsOnly() bool {
+	return signature.pawns == 0 && signature.rooks == 0 && signature.queens == 0
+}
+
+// isDeadDraw reports whether neither side can mate whatever is played: kings alone, a single minor piece,
+// or only bishops that all stand on squares of the same colour.
+func (board *Board) isDeadDraw() bool {
+	white, black := board.materialSignature(false), board.materialSignature(true)
+	if !white.minorsOnly() || !black.minorsOnly() {
+		return false
+	}
+
+	minors := white.knights + white.bishops + black.knights + black.bishops
+	if minors <= 1 {
+		return true
+	}
+
+	bishops := board.WhiteBishops.BitBoard() | board.BlackBishops.BitBoard()
+	return white.knights+black.knights == 0 && (bishops&lightSquares == 0 || bishops&^lightSquares == 0)
+}
+
+// drawScale returns how much of the evaluation to keep, out of scaleNormal: nothing in dead draws and little in
+// endings that are drawn with correct defence, two knights against a bare king and a bishop with rook pawns
+// whose promotion square it does not cover while the defending king holds the corner.
+func (board *Board) drawScale() int32 {
+	if board.isDeadDraw() {
+		return scaleDraw
+	}
+
+	white, black := board.materialSignature(false), board.materialSignature(true)
+	for _, strong := range []struct {
+		black          bool
+		attack, defend materialSignature
+	}{{false, white, black}, {true, black, white}} {
+		if !strong.defend.bareKing() {
+			continue
+		}
+
+		if strong.attack == (materialSignature{knights: 2}) {
+			return scaleLikelyDraw
+		}
+		if strong.attack.bishops == 1 && strong.attack.pawns > 0 && strong.attack.knights+strong.attack.rooks+strong.attack.queens == 0 && board.wrongBishop(strong.black) {
+			return scaleLikelyDraw
+		}
+	}
+
+	return scaleNormal
+}
+
+// wrongBishop reports whether the side's pawns are all on the same rook file, its bishop cannot cover the square
+// they promote on and the other king stands next to that square, from where it cannot be driven away.
+func (board *Board) wrongBishop(black bool) bool {
+	side := board.side(black)
+
+	var corner int
+	switch {
+	case side.pawns&^bitboards.FileMask(0) == 0:
+		corner = 56
+	case side.pawns&^bitboards.FileMask(7) == 0:
+		corner = 63
+	default:
+		return false
+	}
+	if black {
+		corner -= 56
+	}
+
+	cornerLight := bitboards.New(corner)&lightSquares != 0
+	bishopLight := side.bishops&lightSquares != 0
+	if cornerLight == bishopLight {
+		return false
+	}
+
+	king := bits.TrailingZeros64(uint64(board.side(!black).king))
+	return max(abs(king/8-corner/8), abs(king%8-corner%8)) <= 1
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,48 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawScale(t *testing.T) {
+	for _, test := range []struct {
+		fen   string
+		scale int32
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", scaleDraw},
+		{"4k3/8/8/8/8/8/8/2B1K3 w - - 0 1", scaleDraw},
+		{"4k3/8/8/8/8/8/8/1N2K3 b - - 0 1", scaleDraw},
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", scaleDraw},
+		{"4k1b1/8/8/8/8/8/8/2B1K3 w - - 0 1", scaleNormal},
+		{"4k3/8/8/8/8/8/8/1NN1K3 w - - 0 1", scaleLikelyDraw},
+		{"4k3/8/8/8/8/8/8/1NNBK3 w - - 0 1", scaleNormal},
+		// The dark squared bishop cannot drive the king out of a8
+		{"k7/8/8/8/P7/8/8/2B1K3 w - - 0 1", scaleLikelyDraw},
+		{"8/8/4k3/8/P7/8/8/2B1K3 w - - 0 1", scaleNormal},
+		{"k7/8/8/8/P7/8/8/3BK3 w - - 0 1", scaleNormal},
+		// The same for black, promoting on h1
+		{"4k3/8/8/8/7p/8/3b4/6K1 b - - 0 1", scaleLikelyDraw},
+		{"4k3/8/8/8/7p/8/3b4/6K1 w - - 0 1", scaleLikelyDraw},
+		{"4k3/8/8/8/6pp/8/3b4/6K1 w - - 0 1", scaleNormal},
+		{StartingFEN, scaleNormal},
+	} {
+		b, err := FromFEN(test.fen)
+		assert.NoError(t, err)
+		assert.Equal(t, test.scale, b.drawScale(), test.fen)
+	}
+}
+
+func TestEvaluateScalesDraws(t *testing.T) {
+	bishop, err := FromFEN("4k3/8/8/8/8/8/8/2B1K3 w - - 0 1")
+	assert.NoError(t, err)
+	assert.Zero(t, bishop.Evaluate(8, -3, -8, 1).Sum())
+
+	rook, err := FromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	wrongBishop, err := FromFEN("k7/8/8/8/P7/8/8/2B1K3 w - - 0 1")
+	assert.NoError(t, err)
+	assert.Less(t, wrongBishop.Evaluate(8, -3, -8, 1).Sum(), rook.Evaluate(8, -3, -8, 1).Sum()/4)
+}
@@ -46,6 +46,34 @@ func (m Move) IsValid() bool {
 	return m.Source != m.Destination && m.Piece == -1 && m.MoveType != -1
 }
 
+// UCI returns the move in long algebraic notation, e.g. "e2e4" or "e7e8q".
+// The null move is written as "0000".
+func (m Move) UCI() string {
+	if m.Source == m.Destination {
+		return "0000"
+	}
+
+	uci := IndexToPosition(uint64(m.Source)) + IndexToPosition(uint64(m.Destination))
+	if m.MoveType == Promotion {
+		uci += promotionSuffix(m.PromotionPiece)
+	}
+
+	return uci
+}
+
+func promotionSuffix(piece int) string {
+	switch piece {
+	case WhiteKnight, BlackKnight:
+		return "n"
+	case WhiteBishop, BlackBishop:
+		return "b"
+	case WhiteRook, BlackRook:
+		return "r"
+	default:
+		return "q"
+	}
+}
+
 func (board Board) KingInPlayAndOpponentAttacks() (bitboards.BitBoard, bitboards.BitBoard) {
 	if board.TurnBlack {
 		return board.BlackKing.BitBoard(), board.WhiteAttacksMinimal()
@@ -62,266 +90,95 @@ func (board Board) IsAttacked(square, attackedSquares bitboards.BitBoard) bool {
 	}
 }
 
-func (originalBoard Board) AvailableBlackAttacks() bitboards.BitBoard {
-	board := originalBoard
-
-	var attack bitboards.BitBoard
-
-	movesList := board.BlackKnights.Moves(board.EmptySquares, board.WhitePieces)
-	attack = attack | movesList
-
-	movesList = board.BlackBishops.Moves(board.BlackPieces, board.WhitePieces)
-	attack = attack | movesList
-
-	movesList = board.BlackRooks.Moves(board.BlackPieces, board.WhitePieces)
-	attack = attack | movesList
-
-	movesList = board.BlackQueens.Moves(board.BlackPieces, board.WhitePieces)
-	attack = attack | movesList
-
-	movesList = board.BlackPawns.Moves(board.EmptySquares, board.WhitePieces, board.EnPassantTarget)
-	attack = attack | movesList
-
-	return attack
+// isSquareAttacked reports whether any piece of the given colour attacks the square.
+func (board Board) isSquareAttacked(square int, byBlack bool) bool {
+	return board.attackersTo(square, board.OccupiedSquares)&board.side(byBlack).all != 0
 }
 
-func (originalBoard Board) AvailableWhiteAttacks() bitboards.BitBoard {
-	board := originalBoard
-
-	var attack bitboards.BitBoard
-
-	movesList := board.WhiteKnights.Moves(board.EmptySquares, board.BlackPieces)
-	attack = attack | movesList
-
-	movesList = board.WhiteBishops.Moves(board.WhitePieces, board.BlackPieces)
-	attack = attack | movesList
-
-	movesList = board.WhiteRooks.Moves(board.WhitePieces, board.BlackPieces)
-	attack = attack | movesList
-
-	movesList = board.WhiteQueens.Moves(board.WhitePieces, board.BlackPieces)
-	attack = attack | movesList
-
-	movesList = board.WhitePawns.Moves(board.EmptySquares, board.BlackPieces, board.EnPassantTarget)
-	attack = attack | movesList
-
-	return attack
+func (board Board) AvailableBlackAttacks() bitboards.BitBoard {
+	attacks, _ := board.attacksByType(true)
+	return attacks
 }
 
-func (originalBoard Board) AvailableBlackMoves() []Move {
-	board := originalBoard
-	var moves []Move
-
-	attacks := board.WhiteAttacksMinimal()
-	if board.CastleBlackQueenside && !board.isOccupied(58) && !board.isOccupied(59) && !board.isOccupied(57) && board.PieceAt(56) == BlackRook && !board.IsAttacked((board.BlackKing>>1).BitBoard(), attacks) && !board.IsAttacked((board.BlackKing>>2).BitBoard(), attacks) && !board.IsAttacked((board.BlackKing).BitBoard(), attacks) {
-		moves = append(moves, Move{Source: 60, Destination: 58, MoveType: CastleQueenside, Piece: BlackKing})
-	}
-	if board.CastleBlackKingside && !board.isOccupied(61) && !board.isOccupied(62) && !board.IsAttacked((board.BlackKing<<1).BitBoard(), attacks) && board.PieceAt(63) == BlackRook && !board.IsAttacked((board.BlackKing<<2).BitBoard(), attacks) && !board.IsAttacked((board.BlackKing).BitBoard(), attacks) {
-		moves = append(moves, Move{Source: 60, Destination: 62, MoveType: CastleKingside, Piece: BlackKing})
-	}
-
-	knights := board.BlackKnights
-	for knights != 0 {
-		from := knights.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.KnightBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, board.WhitePieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: BlackKnight})
-		}
-	}
-
-	bishops := board.BlackBishops
-	for bishops != 0 {
-		from := bishops.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.BishopBitboard(bitboards.New(int(from))).Moves(board.BlackPieces, board.WhitePieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: BlackBishop})
-		}
-	}
-
-	rooks := board.BlackRooks
-	for rooks != 0 {
-		from := rooks.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.RookBitboard(bitboards.New(int(from))).Moves(board.BlackPieces, board.WhitePieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: BlackRook})
-		}
-	}
-
-	queens := board.BlackQueens
-	for queens != 0 {
-		from := queens.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.QueenBitboard(bitboards.New(int(from))).Moves(board.BlackPieces, board.WhitePieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: BlackQueen})
-		}
-	}
-
-	pawns := board.BlackPawns
-	for pawns != 0 {
-		from := pawns.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.BlackPawnBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, board.WhitePieces, board.EnPassantTarget)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: BlackPawn})
-		}
-	}
-
-	kings := board.BlackKing
-	for kings != 0 {
-		from := kings.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.KingBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, board.WhitePieces)
-		for movesList != 0 {
-			board := board
-			to := movesList.PopLSB()
-
-			move := Move{Source: bits.TrailingZeros64(uint64(board.BlackKing)), Destination: int(to), Piece: BlackKing}
-			board.makeMove(move)
-			if !board.isKingInCheck(board.BlackKing, false) && move.Source != move.Destination {
-				moves = append(moves, move)
-			}
-		}
-	}
-	// Remove attacked
-
-	if board.isKingInCheck(board.BlackKing, false) {
-		var legalMoves []Move
-		for _, move := range moves {
-			tempBoard := board
-			_, err := tempBoard.makeMove(move)
-			if err != nil {
-				panic(err)
-			}
-			// tempBoard.Display()
-			if !board.isKingInCheck(tempBoard.BlackKing, false) {
-				legalMoves = append(legalMoves, move)
-			}
-		}
-		return legalMoves
-	}
-
-	return moves
+func (board Board) AvailableWhiteAttacks() bitboards.BitBoard {
+	attacks, _ := board.attacksByType(false)
+	return attacks
 }
 
-func (originalBoard Board) AvailableWhiteMoves() []Move {
-	board := originalBoard
-	var moves []Move
-
-	attacks := board.BlackAttacksMinimal()
-	if board.CastleWhiteQueenside && board.isOccupied(1) && !board.isOccupied(2) && !board.isOccupied(3) && board.PieceAt(4) == WhiteRook && !board.IsAttacked((board.WhiteKing>>1).BitBoard(), attacks) && !board.IsAttacked((board.WhiteKing>>2).BitBoard(), attacks) {
-		moves = append(moves, Move{Source: 4, Destination: 1, MoveType: CastleQueenside, Piece: WhiteKing})
-	}
-	if board.CastleWhiteKingside && !board.isOccupied(5) && !board.isOccupied(6) && !board.IsAttacked((board.WhiteKing<<1).BitBoard(), attacks) && !board.IsAttacked((board.WhiteKing<<2).BitBoard(), attacks) {
-		moves = append(moves, Move{Source: 4, Destination: 6, MoveType: CastleKingside, Piece: WhiteKing})
-	}
-
-	knights := board.WhiteKnights
-	for knights != 0 {
-		from := knights.BitBoardPointer().PopLSB()
+// attacksByType returns every square attacked by the given colour, along with how many
+// piece types take part in the attack.
+func (board Board) attacksByType(black bool) (bitboards.BitBoard, int) {
+	us := board.side(black)
 
-		movesList := bitboards.KnightBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, board.BlackPieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: WhiteKnight})
-		}
+	pawnAttacks := bitboards.WhitePawnBitboard(us.pawns).Attacks()
+	if black {
+		pawnAttacks = bitboards.BlackPawnBitboard(us.pawns).Attacks()
 	}
 
-	bishops := board.WhiteBishops
-	for bishops != 0 {
-		from := bishops.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.BishopBitboard(bitboards.New(int(from))).Moves(board.WhitePieces, board.BlackPieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: WhiteBishop})
-		}
+	everySquare := ^bitboards.BitBoard(0)
+	attacksPerType := []bitboards.BitBoard{
+		slidingAttacks(us.bishops, board.OccupiedSquares, bitboards.BishopAttacks),
+		bitboards.KingBitboard(us.king).Attacks(everySquare),
+		bitboards.KnightBitboard(us.knights).Attacks(everySquare),
+		pawnAttacks,
+		slidingAttacks(us.queens, board.OccupiedSquares, bitboards.QueenAttacks),
+		slidingAttacks(us.rooks, board.OccupiedSquares, bitboards.RookAttacks),
 	}
 
-	rooks := board.WhiteRooks
-	for rooks != 0 {
-		from := rooks.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.RookBitboard(bitboards.New(int(from))).Moves(board.WhitePieces, board.BlackPieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
-
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: WhiteRook})
+	var attacks bitboards.BitBoard
+	count := 0
+	for _, attacked := range attacksPerType {
+		if attacked != 0 {
+			attacks |= attacked
+			count++
 		}
 	}
 
-	queens := board.WhiteQueens
-	for queens != 0 {
-		from := queens.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.QueenBitboard(bitboards.New(int(from))).Moves(board.WhitePieces, board.BlackPieces)
-		for movesList != 0 {
-			to := movesList.PopLSB()
+	return attacks, count
+}
 
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: WhiteQueen})
-		}
+// slidingAttacks combines the attacks of every slider in pieces using the given lookup.
+func slidingAttacks(pieces, occupied bitboards.BitBoard, attacks func(int, bitboards.BitBoard) bitboards.BitBoard) bitboards.BitBoard {
+	var attacked bitboards.BitBoard
+	for pieces != 0 {
+		attacked |= attacks(int(pieces.PopLSB()), occupied)
 	}
+	return attacked
+}
 
-	pawns := board.WhitePawns
-	for pawns != 0 {
-		from := pawns.BitBoardPointer().PopLSB()
+func (board Board) AvailableBlackMoves() []Move {
+	return board.generateLegalMoves(true)
+}
 
-		movesList := bitboards.WhitePawnBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, board.BlackPieces, board.EnPassantTarget)
-		for movesList != 0 {
-			to := movesList.PopLSB()
+func (board Board) AvailableWhiteMoves() []Move {
+	return board.generateLegalMoves(false)
+}
 
-			moves = append(moves, Move{Source: int(from), Destination: int(to), Piece: WhitePawn})
-		}
+// newMove builds a move of the piece, marking it as a capture when the destination is occupied.
+func (board Board) newMove(from, to, piece int) Move {
+	captured := board.PieceAt(to)
+	if captured != -1 {
+		return Move{Source: from, Destination: to, Piece: piece, CapturedPiece: captured, MoveType: Capture}
 	}
+	return Move{Source: from, Destination: to, Piece: piece, CapturedPiece: -1, MoveType: NormalMove}
+}
 
-	// Remove attacked
-	kings := board.WhiteKing
-	for kings != 0 {
-		from := kings.BitBoardPointer().PopLSB()
-
-		movesList := bitboards.KingBitboard(bitboards.New(int(from))).Moves(board.EmptySquares, board.BlackPieces)
-		for movesList != 0 {
-			board := board
-			to := movesList.PopLSB()
+// appendPawnMoves adds a pawn move, expanding promotions into all four pieces. En passant captures are
+// added by appendEnPassant.
+func (board Board) appendPawnMoves(moves []Move, from, to, piece int) []Move {
+	move := board.newMove(from, to, piece)
 
-			move := Move{Source: bits.TrailingZeros64(uint64(board.WhiteKing)), Destination: int(to), Piece: WhiteKing}
-			board.makeMove(move)
-			if !board.isKingInCheck(board.WhiteKing, true) && move.Source != move.Destination {
-				moves = append(moves, move)
-			}
-		}
-	}
-
-	if board.isKingInCheck(board.WhiteKing, true) {
-		var legalMoves []Move
-		for _, move := range moves {
-			board := board
-			_, err := board.makeMove(move)
-			if err != nil {
-				panic(err)
-			}
-			if !board.isKingInCheck(board.WhiteKing, true) {
-				legalMoves = append(legalMoves, move)
-			}
+	if to < 8 || to >= 56 {
+		move.MoveType = Promotion
+		for _, promotion := range []int{WhiteQueen, WhiteRook, WhiteBishop, WhiteKnight} {
+			// Black pieces directly follow their white counterparts
+			move.PromotionPiece = promotion + piece - WhitePawn
+			moves = append(moves, move)
 		}
-		return legalMoves
+		return moves
 	}
 
-	return moves
+	return append(moves, move)
 }
 
 func (board Board) FromToToMove(from, to bitboards.BitBoard) Move {
@@ -351,108 +208,20 @@ func (board Board) BitBoardMapToMove(opposingColor bitboards.BitBoard, moves map
 }
 
 func (board Board) WhiteAttacks() (bitboards.BitBoard, int) {
-	attacks := bitboards.NewFull()
-	count := 0
-	if bishop := board.WhiteBishops.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.WhiteKing.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.WhiteKnights.Moves(board.EmptySquares, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.WhitePawns.Attacks(); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.WhiteQueens.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.WhiteRooks.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	return attacks, count
+	return board.attacksByType(false)
 }
 
 func (board Board) WhiteAttacksMinimal() bitboards.BitBoard {
-	attacks := bitboards.NewFull()
-	if bishop := board.WhiteBishops.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.WhiteKing.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.WhiteKnights.Moves(board.EmptySquares, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.WhitePawns.Attacks(); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.WhiteQueens.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.WhiteRooks.Moves(board.WhitePieces, board.BlackPieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
+	attacks, _ := board.attacksByType(false)
 	return attacks
 }
 
 func (board Board) BlackAttacks() (bitboards.BitBoard, int) {
-	attacks := bitboards.New(0)
-	count := 0
-	if bishop := board.BlackBishops.Moves(board.BlackPieces, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.BlackKing.Moves(board.EmptySquares, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.BlackKnights.Moves(board.EmptySquares, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.BlackPawns.Attacks(); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.BlackQueens.Moves(board.BlackPieces, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	if bishop := board.BlackRooks.Moves(board.BlackPieces, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-		count++
-	}
-	return attacks, count
+	return board.attacksByType(true)
 }
 
 func (board Board) BlackAttacksMinimal() bitboards.BitBoard {
-	attacks := bitboards.New(0)
-	if bishop := board.BlackBishops.Moves(board.BlackPieces, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.BlackKing.Moves(board.EmptySquares, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.BlackKnights.Moves(board.EmptySquares, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.BlackPawns.Attacks(); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.BlackQueens.Moves(board.BlackPieces, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
-	if bishop := board.BlackRooks.Moves(board.BlackPieces, board.WhitePieces); bishop != 0 {
-		attacks = attacks | bishop
-	}
+	attacks, _ := board.attacksByType(true)
 	return attacks
 }
 
@@ -601,10 +370,15 @@ func (board Board) UCItoMove(uci string) Move {
 		moveType = CastleKingside
 	}
 
-	if (piece == WhiteKing || piece == BlackKing) && (source-3 == destination) {
+	if (piece == WhiteKing || piece == BlackKing) && (source-2 == destination) {
 		moveType = CastleQueenside
 	}
 
+	// A pawn changing file onto an empty square can only be an en passant capture
+	if (piece == WhitePawn || piece == BlackPawn) && !isCapture && source%8 != destination%8 {
+		moveType = EnPassant
+	}
+
 	promotionPiece := -1
 	if len(uci) == 5 {
 		moveType = Promotion
@@ -0,0 +1,211 @@
+package board
+
+import (
+	"slices"
+	"sort"
+)
+
+// historyMax bounds the history scores, bonuses shrink as a score gets close to it.
+const historyMax = 1 << 14
+
+// heuristics remember which quiet moves caused cut-offs, to try them early elsewhere in the tree.
+// Every thread of a search keeps its own, which also sends the helpers of a Lazy SMP search down different lines.
+type heuristics struct {
+	killers      [maxPly + 1][2]PackedMove // The last two quiet moves that cut off at each ply
+	history      [2][64][64]int32          // Cut-off score of quiet moves by side, source and destination
+	counterMoves [12][64]PackedMove        // Quiet move that cut off in reply to a piece moving to a square
+}
+
+// update rewards the quiet move that caused a cut-off and punishes the quiet moves tried before it.
+func (h *heuristics) update(board *Board, move Move, tried []Move, depth, ply int, previous Move) {
+	killers := &h.killers[ply]
+	if packed := move.Pack(); killers[0] != packed {
+		killers[1], killers[0] = killers[0], packed
+	}
+
+	bonus := int32(min(depth*depth, 400))
+	h.addHistory(board.TurnBlack, move, bonus)
+	for _, other := range tried {
+		h.addHistory(board.TurnBlack, other, -bonus)
+	}
+
+	if previous.Source != previous.Destination {
+		h.counterMoves[previous.Piece][previous.Destination] = move.Pack()
+	}
+}
+
+// addHistory moves the history score towards ±historyMax, by less the closer it already is.
+func (h *heuristics) addHistory(black bool, move Move, bonus int32) {
+	entry := &h.history[colourIndex(black)][move.Source][move.Destination]
+	*entry += bonus - *entry*max(bonus, -bonus)/historyMax
+}
+
+func (h *heuristics) historyScore(black bool, move Move) int32 {
+	return h.history[colourIndex(black)][move.Source][move.Destination]
+}
+
+func colourIndex(black bool) int {
+	if black {
+		return 1
+	}
+	return 0
+}
+
+// Stages of the move picker, in the order their moves are returned.
+const (
+	stageTTMove = iota
+	stageGoodCaptures
+	stageKillers
+	stageCounterMove
+	stageQuiets
+	stageBadCaptures
+	stageDone
+)
+
+// movePicker hands out the legal moves of a position best first, sorting a group of moves only once
+// the moves before it failed to cut off: the hash move, captures that do not lose material (see SEE) by most
+// valuable victim, the killer moves and the countermove, the other quiet moves by history and last the losing captures.
+type movePicker struct {
+	board      *Board
+	h          *heuristics
+	stage      int
+	ttMove     PackedMove
+	killers    [2]PackedMove
+	counter    PackedMove
+	captures   []Move // Captures and promotions
+	quiets     []Move
+	badCapture []Move
+	index      int
+}
+
+// newMovePicker generates the legal moves of the position. ply and previous, the move that led to it,
+// select the killer moves and the countermove.
+func newMovePicker(board *Board, h *heuristics, ttMove PackedMove, ply int, previous Move) *movePicker {
+	picker := &movePicker{board: board, h: h, ttMove: ttMove}
+
+	for _, move := range board.LegalMoves() {
+		if isTactical(move) {
+			picker.captures = append(picker.captures, move)
+		} else {
+			picker.quiets = append(picker.quiets, move)
+		}
+	}
+
+	picker.killers = h.killers[ply]
+	if previous.Source != previous.Destination {
+		picker.counter = h.counterMoves[previous.Piece][previous.Destination]
+	}
+
+	return picker
+}
+
+// empty reports whether the side to move has no legal move.
+func (picker *movePicker) empty() bool {
+	return len(picker.captures) == 0 && len(picker.quiets) == 0
+}
+
+// next returns the next move to search, and false once every move was returned.
+func (picker *movePicker) next() (Move, bool) {
+	for {
+		switch picker.stage {
+		case stageTTMove:
+			picker.stage++
+			if move, ok := picker.find(picker.captures, picker.ttMove); ok {
+				return move, true
+			}
+			if move, ok := picker.find(picker.quiets, picker.ttMove); ok {
+				return move, true
+			}
+
+		case stageGoodCaptures:
+			if picker.index == 0 {
+				orderMVVLVA(picker.captures)
+			}
+			for picker.index < len(picker.captures) {
+				move := picker.captures[picker.index]
+				picker.index++
+				if move.Pack() == picker.ttMove {
+					continue
+				}
+				if picker.board.SEE(move) < 0 {
+					picker.badCapture = append(picker.badCapture, move)
+					continue
+				}
+				return move, true
+			}
+			picker.stage++
+			picker.index = 0
+
+		case stageKillers:
+			for picker.index < len(picker.killers) {
+				killer := picker.killers[picker.index]
+				picker.index++
+				if killer == picker.ttMove {
+					continue
+				}
+				if move, ok := picker.find(picker.quiets, killer); ok {
+					return move, true
+				}
+			}
+			picker.stage++
+			picker.index = 0
+
+		case stageCounterMove:
+			picker.stage++
+			if picker.counter == picker.ttMove || slices.Contains(picker.killers[:], picker.counter) {
+				continue
+			}
+			if move, ok := picker.find(picker.quiets, picker.counter); ok {
+				return move, true
+			}
+
+		case stageQuiets:
+			if picker.index == 0 {
+				black := picker.board.TurnBlack
+				sort.SliceStable(picker.quiets, func(i, j int) bool {
+					return picker.h.historyScore(black, picker.quiets[i]) > picker.h.historyScore(black, picker.quiets[j])
+				})
+			}
+			for picker.index < len(picker.quiets) {
+				move := picker.quiets[picker.index]
+				picker.index++
+				if packed := move.Pack(); packed == picker.ttMove || packed == picker.counter || slices.Contains(picker.killers[:], packed) {
+					continue
+				}
+				return move, true
+			}
+			picker.stage++
+			picker.index = 0
+
+		case stageBadCaptures:
+			if picker.index < len(picker.badCapture) {
+				picker.index++
+				return picker.badCapture[picker.index-1], true
+			}
+			picker.stage++
+
+		default:
+			return Move{}, false
+		}
+	}
+}
+
+// find returns the move of moves that packs to packed.
+func (picker *movePicker) find(moves []Move, packed PackedMove) (Move, bool) {
+	if packed == 0 {
+		return Move{}, false
+	}
+
+	for _, move := range moves {
+		if move.Pack() == packed {
+			return move, true
+		}
+	}
+
+	return Move{}, false
+}
+
+// isTactical reports whether the move changes the material, by capturing or promoting.
+func isTactical(move Move) bool {
+	return capturedValue(move) > 0 || move.MoveType == Promotion
+}
@@ -0,0 +1,71 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pickAll(picker *movePicker) []string {
+	var ucis []string
+	for move, ok := picker.next(); ok; move, ok = picker.next() {
+		ucis = append(ucis, move.UCI())
+	}
+	return ucis
+}
+
+func TestMovePickerReturnsEveryMoveOnce(t *testing.T) {
+	b, err := FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	assert.NoError(t, err)
+
+	var legal []string
+	for _, move := range b.LegalMoves() {
+		legal = append(legal, move.UCI())
+	}
+
+	tt := Move{Source: 12, Destination: 26}.Pack() // e2c4
+	picked := pickAll(newMovePicker(&b, &heuristics{}, tt, 0, Move{}))
+	assert.ElementsMatch(t, legal, picked)
+	assert.Equal(t, "e2c4", picked[0])
+}
+
+func TestMovePickerStages(t *testing.T) {
+	// Rxa5 wins a knight for free, Qxd5 gives the queen for a pawn
+	b, err := FromFEN("4k3/8/4p3/n2p4/8/8/8/R2QK3 w - - 0 1")
+	assert.NoError(t, err)
+
+	h := &heuristics{}
+	killer := Move{Source: 3, Destination: 19, Piece: WhiteQueen} // d1d3
+	h.update(&b, killer, nil, 4, 2, Move{})
+
+	picked := pickAll(newMovePicker(&b, h, 0, 2, Move{}))
+	assert.Equal(t, "a1a5", picked[0], "winning capture first")
+	assert.Equal(t, "d1d3", picked[1], "then the killer")
+	assert.Equal(t, "d1d5", picked[len(picked)-1], "losing capture last")
+}
+
+func TestHeuristicsUpdate(t *testing.T) {
+	b := New()
+	h := &heuristics{}
+
+	previous := Move{Source: 52, Destination: 36, Piece: BlackPawn}
+	first := Move{Source: 6, Destination: 21, Piece: WhiteKnight}
+	second := Move{Source: 1, Destination: 18, Piece: WhiteKnight}
+	tried := Move{Source: 12, Destination: 20, Piece: WhitePawn}
+
+	h.update(&b, first, nil, 3, 5, previous)
+	h.update(&b, second, []Move{tried}, 3, 5, previous)
+
+	assert.Equal(t, second.Pack(), h.killers[5][0])
+	assert.Equal(t, first.Pack(), h.killers[5][1])
+	assert.Equal(t, second.Pack(), h.counterMoves[BlackPawn][36])
+	assert.Greater(t, h.historyScore(false, first), int32(0))
+	assert.Less(t, h.historyScore(false, tried), int32(0))
+	assert.Zero(t, h.historyScore(true, first))
+
+	// History saturates instead of overflowing
+	for i := 0; i < 10000; i++ {
+		h.addHistory(false, first, 400)
+	}
+	assert.LessOrEqual(t, h.historyScore(false, first), int32(historyMax))
+}
@@ -1,9 +1,11 @@
 package board
 
 import (
+	"cmp"
 	"fmt"
-	"math/rand"
-	"sync"
+	"math"
+	"slices"
+	"time"
 
 	"engine/evaluation/board/bitboards"
 )
@@ -29,8 +31,12 @@ type MoveUndo struct {
 	PreviousCastleWhiteQueenside bool
 	PreviousCastleBlackKingside  bool
 	PreviousCastleBlackQueenside bool
+	PreviousWhiteCastled         bool
+	PreviousBlackCastled         bool
 	PreviousTurnBlack            bool
 	PreviousHalfTurn             int
+	PreviousHalfMoveClock        int
+	PreviousHash                 uint64
 	PreviousAggregateBitboards   AggregateBitboards
 }
 
@@ -38,13 +44,36 @@ func (board *Board) UndoMove(undo *MoveUndo) {
 	*board.pieceBitboard(undo.Piece) &= ^bitboards.New(undo.Destination)
 	*board.pieceBitboard(undo.Piece) |= bitboards.New(undo.Source)
 
+	if undo.MoveType == Promotion {
+		*board.pieceBitboard(undo.PromotionPiece) &= ^bitboards.New(undo.Destination)
+	}
+
 	if undo.CapturedPiece != -1 {
 		*board.pieceBitboard(undo.CapturedPiece) |= bitboards.New(undo.Destination)
 	}
 
-	if undo.MoveType == Promotion {
-		*board.pieceBitboard(undo.PromotionPiece) &= ^bitboards.New(undo.Destination)
-		*board.pieceBitboard(undo.Piece) |= bitboards.New(undo.Destination)
+	if undo.MoveType == EnPassant {
+		if undo.Piece == WhitePawn {
+			*board.pieceBitboard(BlackPawn) |= bitboards.New(undo.Destination - 8)
+		} else {
+			*board.pieceBitboard(WhitePawn) |= bitboards.New(undo.Destination + 8)
+		}
+	}
+
+	// Put the rook back in its corner after castling
+	switch {
+	case undo.MoveType == CastleKingside && undo.Piece == WhiteKing:
+		*board.pieceBitboard(WhiteRook) &= ^bitboards.New(5)
+		*board.pieceBitboard(WhiteRook) |= bitboards.New(7)
+	case undo.MoveType == CastleQueenside && undo.Piece == WhiteKing:
+		*board.pieceBitboard(WhiteRook) &= ^bitboards.New(3)
+		*board.pieceBitboard(WhiteRook) |= bitboards.New(0)
+	case undo.MoveType == CastleKingside && undo.Piece == BlackKing:
+		*board.pieceBitboard(BlackRook) &= ^bitboards.New(61)
+		*board.pieceBitboard(BlackRook) |= bitboards.New(63)
+	case undo.MoveType == CastleQueenside && undo.Piece == BlackKing:
+		*board.pieceBitboard(BlackRook) &= ^bitboards.New(59)
+		*board.pieceBitboard(BlackRook) |= bitboards.New(56)
 	}
 
 	// Restore castling rights
@@ -52,200 +81,553 @@ func (board *Board) UndoMove(undo *MoveUndo) {
 	board.CastleWhiteQueenside = undo.PreviousCastleWhiteQueenside
 	board.CastleBlackKingside = undo.PreviousCastleBlackKingside
 	board.CastleBlackQueenside = undo.PreviousCastleBlackQueenside
+	board.WhiteCastled = undo.PreviousWhiteCastled
+	board.BlackCastled = undo.PreviousBlackCastled
 
 	// Restore turn and half turn counters
 	board.TurnBlack = undo.PreviousTurnBlack
 	board.HalfTurn = undo.PreviousHalfTurn
+	board.HalfMoveClock = undo.PreviousHalfMoveClock
+	board.Hash = undo.PreviousHash
+	if len(board.History) > 0 {
+		board.History = board.History[:len(board.History)-1]
+	}
 
 	// Restore aggregate bitboards if needed
 	board.BlackPieces = undo.PreviousAggregateBitboards.BlackPieces
 	board.EmptySquares = undo.PreviousAggregateBitboards.EmptySquares
-	board.EnPassantTarget = undo.PreviousAggregateBitboards.EnPassantTarget
 	board.OccupiedSquares = undo.PreviousAggregateBitboards.OccupiedSquares
 	board.WhitePieces = undo.PreviousAggregateBitboards.WhitePieces
 
 	// Ensure the board's internal state is consistent
 	board.updateAggregateBitboards()
+	board.EnPassantTarget = undo.PreviousAggregateBitboards.EnPassantTarget
 }
 
 type MoveEvaluation struct {
 	Move  Move
-	Score Evaluation
-}
-
-type TranspositionEntry struct {
-	Depth    int
-	Score    Evaluation
-	Flag     int
-	BestMove Move
+	Score int32
+	PV    []Move // Principal variation starting with Move
 }
 
 const (
-	exact      = 0
-	lowerBound = 1
-	upperBound = 2
+	// MateScore is the score of mating on the spot, a mate n plies away scores MateScore - n.
+	MateScore int32 = 32000
+	// Infinity is beyond any score the search can return.
+	Infinity int32 = MateScore + 1
+	// maxPly bounds how deep the search can go, and so how far away a mate score can be.
+	maxPly = 128
 )
 
-var zobristTable [64][12]uint64 // 64 squares, 12 possible pieces (6 white, 6 black)
+// IsMateScore reports whether the score is a forced mate for either side.
+func IsMateScore(score int32) bool {
+	return score > MateScore-maxPly || score < -MateScore+maxPly
+}
 
-func InitZobristTable() {
-	for i := 0; i < 64; i++ {
-		for j := 0; j < 12; j++ {
-			zobristTable[i][j] = uint64(rand.Uint32())<<32 + uint64(rand.Uint32()) // Assume randUint64() generates a random 64-bit number
-		}
+// MateIn converts a mate score to full moves, negative when the side to move is getting mated.
+func MateIn(score int32) int {
+	if score > 0 {
+		return int(MateScore-score+1) / 2
 	}
+	return -int(MateScore+score+1) / 2
 }
 
-func (board *Board) hash() uint64 {
-	var h uint64
-	for i := 0; i < 64; i++ {
-		if piece := board.PieceAt(i); piece != -1 {
-			h ^= zobristTable[i][piece]
+// BestMove searches every root move to the given depth and returns the best one with its score
+// from the point of view of the side to move and its principal variation.
+func (board *Board) BestMove(depth int, materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) SearchResult {
+	s := newSearch(DefaultSearchOptions(), materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+	best, _ := board.searchRoot(depth, s, Move{}, nil, -Infinity, Infinity)
+	return SearchResult{Depth: depth + 1, Move: best.Move, Score: best.Score, PV: best.PV, Lines: []MoveEvaluation{best}, Nodes: s.nodes.Load(), Stats: s.stats.snapshot()}
+}
+
+// searchLines finds the best SearchOptions.MultiPV lines, searching the root once per line and leaving out
+// the moves of the lines already found. previous holds the lines of the last iteration, whose moves are tried first.
+func (board *Board) searchLines(depth int, s *search, previous []MoveEvaluation) ([]MoveEvaluation, bool) {
+	var lines []MoveEvaluation
+	var excluded []Move
+
+	for len(lines) < max(s.options.MultiPV, 1) {
+		var best MoveEvaluation
+		var completed bool
+		if len(lines) < len(previous) {
+			best, completed = board.searchAspiration(depth, s, previous[len(lines)], excluded)
+		} else {
+			best, completed = board.searchRoot(depth, s, Move{}, excluded, -Infinity, Infinity)
+		}
+		if !completed {
+			return nil, false
+		}
+
+		// Out of root moves, only the first pass reports a mate or stalemate without a move
+		if best.Move.Source == best.Move.Destination {
+			if len(lines) == 0 {
+				lines = append(lines, best)
+			}
+			break
 		}
+
+		lines = append(lines, best)
+		excluded = append(excluded, best.Move)
 	}
-	return h
-}
 
-var (
-	TranspositionTable = make(map[uint64]TranspositionEntry)
-	tableLock          = sync.RWMutex{} // Mutex to protect map access
-)
+	// Hash entries from other passes can make a later line score better than an earlier one
+	slices.SortStableFunc(lines, func(a, b MoveEvaluation) int { return cmp.Compare(b.Score, a.Score) })
 
-func getTranspositionEntry(hashKey uint64) (TranspositionEntry, bool) {
-	tableLock.RLock()
-	entry, exists := TranspositionTable[hashKey]
-	tableLock.RUnlock()
-	return entry, exists
+	return lines, true
 }
 
-func setTranspositionEntry(hashKey uint64, entry TranspositionEntry) {
-	tableLock.Lock()
-	TranspositionTable[hashKey] = entry
-	tableLock.Unlock()
+// aspirationMinDepth is the shallowest search below the root that uses an aspiration window, the scores of
+// the first iterations swing too much for a narrow window to hold.
+const aspirationMinDepth = 3
+
+// searchAspiration searches the root with a narrow window around the score of the same line in the previous
+// iteration, which cuts off more of the tree than a full window does. When the score falls outside the window
+// the search is repeated with the failing side moved out twice as far each time, until it is fully open.
+func (board *Board) searchAspiration(depth int, s *search, previous MoveEvaluation, excluded []Move) (MoveEvaluation, bool) {
+	alpha, beta := -Infinity, Infinity
+	delta := s.options.AspirationWindow
+	if delta > 0 && depth >= aspirationMinDepth && !IsMateScore(previous.Score) {
+		alpha, beta = max(previous.Score-delta, -Infinity), min(previous.Score+delta, Infinity)
+	}
+
+	first := previous.Move
+	for {
+		best, completed := board.searchRoot(depth, s, first, excluded, alpha, beta)
+		if !completed || best.Move.Source == best.Move.Destination {
+			return best, completed
+		}
+
+		delta *= 2
+		switch {
+		case best.Score <= alpha && alpha > -Infinity:
+			s.stats.aspirationFailLows.Add(1)
+			alpha = max(alpha-delta, -Infinity)
+			if IsMateScore(best.Score) {
+				alpha = -Infinity
+			}
+		case best.Score >= beta && beta < Infinity:
+			s.stats.aspirationFailHighs.Add(1)
+			beta = min(beta+delta, Infinity)
+			if IsMateScore(best.Score) {
+				beta = Infinity
+			}
+			// The move that failed high is the one to beat
+			first = best.Move
+		default:
+			return best, true
+		}
+	}
 }
 
-func (board *Board) BestMove(depth int, strategy func(Board) []Move, materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) (Move, Evaluation) {
-	InitZobristTable()
-	legalMoves := strategy(*board)
+// searchRoot searches the root moves one after the other, leaving out the excluded ones. first is searched
+// first with the full window. With SearchOptions.PVS the others only have to prove they are no better than
+// the best score so far, which is done with a null window and repeated with the full window on a fail high.
+// Helper threads of a Lazy SMP search try the other moves in a different order, see helperOrder.
+// Scores outside the alpha-beta window are bounds, as with negamax. It reports false when the search was
+// stopped before every root move was searched, in which case the result must not be used.
+func (board *Board) searchRoot(depth int, s *search, first Move, excluded []Move, alpha, beta int32) (MoveEvaluation, bool) {
+	// The root moves come in the order of the move picker, with first, or else the hash move, in front
+	ttMove := first.Pack()
+	if entry, exists := TranspositionTable.Probe(board.Hash); ttMove == 0 && exists {
+		ttMove = entry.BestMove
+	}
+	picker := newMovePicker(board, &s.heuristics, ttMove, 0, Move{})
+	if picker.empty() {
+		if board.inCheck() {
+			return MoveEvaluation{Score: -MateScore}, true
+		}
+		return MoveEvaluation{}, true
+	}
+
+	var legalMoves []Move
+	for move, ok := picker.next(); ok; move, ok = picker.next() {
+		if !slices.ContainsFunc(excluded, func(other Move) bool { return other.Pack() == move.Pack() }) {
+			legalMoves = append(legalMoves, move)
+		}
+	}
 	if len(legalMoves) == 0 {
-		return Move{}, Evaluation{} // or appropriate error handling
+		return MoveEvaluation{}, true
+	}
+	if s.helper > 0 {
+		helperOrder(legalMoves, s.helper)
 	}
 
-	results := make(chan MoveEvaluation, len(legalMoves))
-	defer close(results)
+	// Every thread gets its own history to append to
+	tmpBoard := *board
+	s.extensions[1] = 0
+	tmpBoard.History = slices.Clip(tmpBoard.History)
+
+	best := MoveEvaluation{Score: -Infinity}
+	for i, move := range legalMoves {
+		if s.info != nil && time.Since(s.start) >= currMoveDelay {
+			info := s.newInfo(depth + 1)
+			info.CurrMove, info.CurrMoveNumber = move, i+1
+			s.info(info)
+		}
 
-	for _, move := range legalMoves {
-		go func(move Move) {
-			tmpBoard := *board
-			undo, err := tmpBoard.MakeNativeMove(move)
-			if err != nil {
-				panic(err)
+		undo, err := tmpBoard.MakeNativeMove(move)
+		if err != nil {
+			panic(err)
+		}
+
+		var line []Move
+		var score int32
+		if !s.options.PVS || i == 0 {
+			score = -tmpBoard.negamax(depth, -beta, -alpha, 1, move, s, &line)
+		} else {
+			score = -tmpBoard.negamax(depth, -alpha-1, -alpha, 1, move, s, &line)
+			if score > alpha && score < beta {
+				s.stats.researches.Add(1)
+				line = line[:0]
+				score = -tmpBoard.negamax(depth, -beta, -alpha, 1, move, s, &line)
 			}
-			score := tmpBoard.MiniMax(depth, -9999, 9999, false, strategy, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
-			tmpBoard.UndoMove(undo)
-			results <- MoveEvaluation{Move: move, Score: score}
-		}(move)
-	}
+		}
+		tmpBoard.UndoMove(undo)
+
+		if s.aborted.Load() {
+			return MoveEvaluation{}, false
+		}
+
+		if board.Debug && s.helper == 0 {
+			fmt.Println(PieceSymbols[board.PieceAt(int(move.Source))], "(", IndexToPosition(uint64(move.Destination)), ") score: ", score, ", pv: ", FormatPV(append([]Move{move}, line...)))
+		}
 
-	// Find the best move based on evaluations
-	bestMove := Move{}
-	bestScore := Evaluation{-128, -128, -128, -128, -128, -128}
+		// On equal scores the earlier move in the ordering wins
+		if score > best.Score {
+			best = MoveEvaluation{Move: move, Score: score, PV: append([]Move{move}, line...)}
+		}
+		alpha = max(alpha, score)
 
-	for range legalMoves {
-		result := <-results
-		if board.Debug {
-			fmt.Println(PieceSymbols[board.PieceAt(int(result.Move.Source))], "(", IndexToPosition(uint64(result.Move.Destination)), ") material: ", result.Score.material, ", centre bonus: ", result.Score.centreBonus, ", mobility bonus: ", result.Score.mobilityBonus, ", pawn structure bonus: ", result.Score.pawnPenalties, ", knight placement bonus: ", result.Score.knightBonus, ", king safety bonus: ", result.Score.safety)
+		// Nothing can beat a move that already fails high
+		if alpha >= beta {
+			break
 		}
-		if result.Score.Sum() > bestScore.Sum() {
-			bestScore = result.Score
-			bestMove = result.Move
+	}
+
+	// Cut-offs on exact hash entries end a line early, the rest of it is still in the table
+	if len(best.PV) <= depth {
+		tmpBoard := *board
+		tmpBoard.History = slices.Clip(tmpBoard.History)
+		for _, move := range best.PV {
+			if _, err := tmpBoard.makeMove(move); err != nil {
+				panic(err)
+			}
 		}
+		best.PV = append(best.PV, tmpBoard.pvFromTT(depth+1-len(best.PV))...)
 	}
 
-	return bestMove, bestScore
+	return best, true
 }
 
-func max(a, b int16) int16 {
-	if a > b {
-		return a
+// helperOrder rotates every move but the first by the number of the helper thread, so that helpers
+// spread over the root moves instead of all searching them in the order of the main thread.
+func helperOrder(moves []Move, helper int) {
+	if len(moves) < 3 {
+		return
 	}
-	return b
+	rest := moves[1:]
+	shift := helper % len(rest)
+	slices.Reverse(rest[:shift])
+	slices.Reverse(rest[shift:])
+	slices.Reverse(rest)
 }
 
-func min(a, b int16) int16 {
-	if a < b {
-		return a
+// negamax returns the score of the position from the point of view of the side to move, ply half moves from the root.
+// Scores outside the alpha-beta window are bounds: at most alpha when every move failed low, at least beta after a cut-off.
+// The principal variation below this node is written to pv when a move raises alpha.
+// previous is the move that led to the position, a null Move{} after a null move so that two never follow each other.
+func (board *Board) negamax(depth int, alpha, beta int32, ply int, previous Move, s *search, pv *[]Move) int32 {
+	s.selDepth = max(s.selDepth, ply)
+
+	// A position seen before in the game or the search is taken to be a draw, playing on would repeat it again.
+	// The fifty-move rule only applies when the move that reached the limit did not mate.
+	if ply > 0 && board.IsFiftyMoveDraw() {
+		if board.inCheck() && len(board.LegalMoves()) == 0 {
+			return -MateScore + int32(ply)
+		}
+		return 0
+	}
+	if ply > 0 && (board.repetitions() > 0 || board.isDeadDraw()) {
+		return 0
+	}
+	if depth <= 0 || ply >= maxPly {
+		return board.quiescence(alpha, beta, ply, s.options.QuiescenceChecks, s)
+	}
+	if s.shouldStop() {
+		return 0
 	}
-	return b
-}
 
-func (board *Board) MiniMax(depth int, alpha, beta int16, maximizingPlayer bool, strategy func(Board) []Move, materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) Evaluation {
-	if depth == 0 {
-		return board.Evaluate(materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+	originalAlpha := alpha
+	hashKey := board.Hash
+	entry, exists := TranspositionTable.Probe(hashKey)
+	// A search leaving out a move cannot use what was found with it
+	if exists && entry.Depth >= depth && s.excluded[ply] == 0 {
+		score := scoreFromTT(entry.Score, ply)
+		switch {
+		case entry.Flag == exact,
+			entry.Flag == lowerBound && score >= beta,
+			entry.Flag == upperBound && score <= alpha:
+			// The line the entry stands for reached at least as deep as this search would have
+			s.selDepth = max(s.selDepth, ply+entry.Depth)
+			return score
+		}
 	}
-	hashKey := board.hash()
-	if entry, exists := getTranspositionEntry(hashKey); exists && entry.Depth >= depth {
-		switch entry.Flag {
-		case exact:
-			return entry.Score
-		case lowerBound:
-			alpha = max(alpha, entry.Score.Sum())
-		case upperBound:
-			beta = min(beta, entry.Score.Sum())
+
+	// Only null windows are pruned, the principal variation is always searched in full
+	inCheck := board.inCheck()
+	pvNode := beta-alpha > 1
+	var staticEval int32
+	if !inCheck && !pvNode {
+		staticEval = s.evaluate(board)
+
+		// Reverse futility pruning: this close to the leaves the opponent is unlikely to win back a big margin
+		if s.options.ReverseFutility && depth <= reverseFutilityMaxDepth && !IsMateScore(beta) && staticEval-reverseFutilityMargin*int32(depth) >= beta {
+			s.stats.reverseFutilityPrunes.Add(1)
+			return staticEval
 		}
 
-		if alpha >= beta {
-			return entry.Score
+		if previous.Source != previous.Destination && s.excluded[ply] == 0 && s.options.NullMove && depth >= nullMoveMinDepth && board.hasNonPawnMaterial() {
+			if score, pruned := board.nullMovePrune(depth, beta, staticEval, ply, s); pruned {
+				return score
+			}
 		}
 	}
-	legalMoves := strategy(*board)
-	if len(legalMoves) == 0 {
-		return board.Evaluate(materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+
+	// Futility pruning: quiet moves cannot make up for a static evaluation this far below alpha
+	futile := !inCheck && !pvNode && s.options.Futility && depth <= futilityMaxDepth && !IsMateScore(alpha) && staticEval+futilityMargin*int32(depth) <= alpha
+
+	// The best move stored for this position is likely to be best again
+	picker := newMovePicker(board, &s.heuristics, entry.BestMove, ply, previous)
+	if picker.empty() {
+		if inCheck {
+			return -MateScore + int32(ply)
+		}
+		return 0
 	}
 
-	if maximizingPlayer {
-		maxEval := Evaluation{material: -128, pawnPenalties: -128, mobilityBonus: -128, centreBonus: -128, safety: -128, knightBonus: -128}
-		var bestMove Move
-		for _, move := range legalMoves {
-			tmpBoard := *board
-			undo, err := tmpBoard.MakeNativeMove(move)
-			if err != nil {
-				panic(err) // Handle the error appropriately.
+	// Singular extension: a hash move far better than every other move is searched one ply deeper
+	singular := s.options.SingularExtensions && ply > 0 && s.excluded[ply] == 0 && depth >= singularMinDepth &&
+		exists && entry.BestMove != 0 && entry.Flag != upperBound && entry.Depth >= depth-singularDepthSlack &&
+		!IsMateScore(entry.Score) && s.extensions[ply] < s.options.ExtensionBudget &&
+		board.isSingular(entry, depth, ply, previous, s)
+
+	bestScore := -Infinity
+	var bestMove Move
+	var quietsTried []Move
+	for i := 0; ; i++ {
+		move, ok := picker.next()
+		if !ok {
+			break
+		}
+		if move.Pack() == s.excluded[ply] {
+			continue
+		}
+
+		tmpBoard := *board
+		undo, err := tmpBoard.MakeNativeMove(move)
+		if err != nil {
+			panic(err) // Handle the error appropriately.
+		}
+
+		// Forcing moves are searched deeper, as long as the path has extensions left
+		extension := 0
+		if s.extensions[ply] < s.options.ExtensionBudget {
+			if singular && move.Pack() == entry.BestMove {
+				s.stats.singularExtensions.Add(1)
+				extension = 1
+			} else if s.options.Extensions {
+				extension = board.extension(move, &tmpBoard, previous, pvNode)
 			}
-			eval := tmpBoard.MiniMax(depth-1, -beta, -alpha, false, strategy, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+		}
+		if extension > 0 {
+			s.stats.extensions.Add(1)
+		}
+		s.extensions[ply+1] = s.extensions[ply] + extension
+		newDepth := depth - 1 + extension
+
+		quiet := !isTactical(move) && !tmpBoard.inCheck() && extension == 0
+
+		if futile && quiet && i > 0 {
 			tmpBoard.UndoMove(undo)
+			s.stats.futilityPrunes.Add(1)
+			bestScore = max(bestScore, staticEval+futilityMargin*int32(depth))
+			continue
+		}
 
-			if eval.Sum() > maxEval.Sum() {
-				maxEval = eval
-			}
-			alpha = max(alpha, eval.Sum())
-			if beta <= alpha {
-				break // alpha cut-off
+		// Late quiet moves are unlikely to be good, a shallower search is enough to show it
+		reduction := 0
+		if s.options.LMR && i >= lmrMinMoves && depth >= lmrMinDepth && quiet && !inCheck {
+			reduction = lateMoveReduction(depth, i, pvNode)
+		}
+
+		var line []Move
+		var score int32
+		if reduction > 0 {
+			s.stats.reductions.Add(1)
+			score = -tmpBoard.negamax(newDepth-reduction, -alpha-1, -alpha, ply+1, move, s, &line)
+			if score > alpha {
+				s.stats.reductionResearches.Add(1)
+				line = line[:0]
 			}
 		}
-		setTranspositionEntry(hashKey, TranspositionEntry{Depth: depth, Score: maxEval, Flag: exact, BestMove: bestMove})
-		return maxEval
-	} else {
-		minEval := Evaluation{material: 127, pawnPenalties: 127, mobilityBonus: 127, centreBonus: 127, safety: 127, knightBonus: 127}
-		var bestMove Move
-		for _, move := range legalMoves {
-			tmpBoard := *board
-			undo, err := tmpBoard.MakeNativeMove(move)
-			if err != nil {
-				panic(err) // Handle the error appropriately.
+		if reduction == 0 || score > alpha {
+			if i == 0 || !s.options.PVS {
+				score = -tmpBoard.negamax(newDepth, -beta, -alpha, ply+1, move, s, &line)
+			} else {
+				// The first move is most likely the best, the others only have to be shown to be worse
+				score = -tmpBoard.negamax(newDepth, -alpha-1, -alpha, ply+1, move, s, &line)
+				if score > alpha && score < beta {
+					s.stats.researches.Add(1)
+					line = line[:0]
+					score = -tmpBoard.negamax(newDepth, -beta, -alpha, ply+1, move, s, &line)
+				}
 			}
-			eval := tmpBoard.MiniMax(depth-1, -beta, -alpha, true, strategy, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
-			tmpBoard.UndoMove(undo)
+		}
+		tmpBoard.UndoMove(undo)
+		if s.aborted.Load() {
+			return 0
+		}
 
-			if eval.Sum() < minEval.Sum() {
-				minEval = eval
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+		if score > alpha {
+			alpha = score
+			*pv = append(append((*pv)[:0], move), line...)
+		}
+		if alpha >= beta {
+			s.stats.betaCutoffs.Add(1)
+			if i == 0 {
+				s.stats.firstMoveCutoffs.Add(1)
 			}
-			beta = min(beta, eval.Sum())
-			if alpha >= beta {
-				break // beta cut-off
+			if !isTactical(move) {
+				s.heuristics.update(board, move, quietsTried, depth, ply, previous)
 			}
+			break // beta cut-off
+		}
+		if !isTactical(move) {
+			quietsTried = append(quietsTried, move)
 		}
-		setTranspositionEntry(hashKey, TranspositionEntry{Depth: depth, Score: minEval, Flag: exact, BestMove: bestMove})
-		return minEval
 	}
+
+	flag := exact
+	switch {
+	case bestScore <= originalAlpha:
+		// No move reached alpha, so none of them is known to be best
+		flag = upperBound
+		bestMove = Move{}
+	case bestScore >= beta:
+		flag = lowerBound
+	}
+	if s.excluded[ply] == 0 {
+		TranspositionTable.Store(hashKey, TranspositionEntry{Depth: depth, Score: scoreToTT(bestScore, ply), Flag: flag, BestMove: bestMove.Pack()})
+	}
+
+	return bestScore
+}
+
+const (
+	// nullMoveMinDepth is the shallowest node that tries a null move, below it the reduced search is all quiescence.
+	nullMoveMinDepth = 3
+	// nullMoveVerificationDepth is the depth from which a null move cut-off is checked by a reduced real search.
+	nullMoveVerificationDepth = 8
+)
+
+// nullMovePrune lets the opponent move twice in a row. If the position still fails high the side to move is
+// so far ahead that a real move would fail high too, which is proved with a search reduced by a few plies.
+// That does not hold in zugzwang, where every move makes things worse: the caller does not try it in check or
+// with only pawns left, and deep nodes verify the cut-off with a reduced search without null moves.
+// It returns the score to fail high with and whether the node can be pruned.
+func (board *Board) nullMovePrune(depth int, beta, staticEval int32, ply int, s *search) (int32, bool) {
+	if staticEval < beta {
+		return 0, false
+	}
+
+	// Deep nodes and positions far above beta can afford a bigger reduction
+	reduction := 2 + depth/4 + min(int(staticEval-beta)/200, 2)
+
+	undo := board.MakeNullMove()
+	s.extensions[ply+1] = s.extensions[ply]
+	score := -board.negamax(depth-1-reduction, -beta, -beta+1, ply+1, Move{}, s, new([]Move))
+	board.UndoNullMove(undo)
+	if s.aborted.Load() || score < beta {
+		return 0, false
+	}
+
+	// Passing cannot prove a mate, only that the position is good enough
+	if IsMateScore(score) {
+		score = beta
+	}
+
+	if depth >= nullMoveVerificationDepth {
+		verified := board.negamax(depth-reduction, beta-1, beta, ply, Move{}, s, new([]Move))
+		if s.aborted.Load() || verified < beta {
+			return 0, false
+		}
+	}
+
+	s.stats.nullMoveCutoffs.Add(1)
+	return score, true
+}
+
+const (
+	// futilityMaxDepth is the deepest node where quiet moves are pruned for futility.
+	futilityMaxDepth = 2
+	// futilityMargin is what a quiet move is assumed to gain at most per ply left, in centipawns.
+	futilityMargin int32 = 200
+	// reverseFutilityMaxDepth is the deepest node that fails high on its static evaluation alone.
+	reverseFutilityMaxDepth = 3
+	// reverseFutilityMargin is what the opponent is assumed to win back at most per ply left, in centipawns.
+	reverseFutilityMargin int32 = 150
+
+	// lmrMinDepth is the shallowest node where late moves are reduced.
+	lmrMinDepth = 3
+	// lmrMinMoves is how many moves are searched to full depth before the rest are reduced.
+	lmrMinMoves = 3
+)
+
+// lmrReductions holds the reduction by depth and move number, growing with the logarithm of both.
+var lmrReductions [64][64]int
+
+func init() {
+	for depth := 1; depth < 64; depth++ {
+		for moveNumber := 1; moveNumber < 64; moveNumber++ {
+			lmrReductions[depth][moveNumber] = int(0.5 + math.Log(float64(depth))*math.Log(float64(moveNumber))/2.25)
+		}
+	}
+}
+
+// lateMoveReduction returns how many plies to reduce the search of the move at index moveNumber. Nodes on the
+// principal variation are reduced by one ply less, and there is always at least one ply left to search.
+func lateMoveReduction(depth, moveNumber int, pvNode bool) int {
+	reduction := lmrReductions[min(depth, 63)][min(moveNumber, 63)]
+	if pvNode {
+		reduction--
+	}
+
+	return max(min(reduction, depth-2), 0)
+}
+
+// scoreToTT makes a mate score relative to the position being stored, so it stays right when the position
+// is reached again at another distance from the root.
+func scoreToTT(score int32, ply int) int32 {
+	switch {
+	case score > MateScore-maxPly:
+		return score + int32(ply)
+	case score < -MateScore+maxPly:
+		return score - int32(ply)
+	}
+	return score
+}
+
+// scoreFromTT turns a stored mate score back into one relative to the root.
+func scoreFromTT(score int32, ply int) int32 {
+	switch {
+	case score > MateScore-maxPly:
+		return score - int32(ply)
+	case score < -MateScore+maxPly:
+		return score + int32(ply)
+	}
+	return score
 }
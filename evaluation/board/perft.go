@@ -0,0 +1,45 @@
+package board
+
+// Perft counts the leaf nodes of the legal move tree to the given depth.
+// The counts can be compared with published values to verify the move generator.
+func (board Board) Perft(depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	moves := board.LegalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, move := range moves {
+		undo, err := board.makeMove(move)
+		if err != nil {
+			panic(err)
+		}
+		nodes += board.Perft(depth - 1)
+		board.UndoMove(undo)
+	}
+
+	return nodes
+}
+
+// Divide returns the perft count below each root move, keyed by the move in UCI notation.
+func (board Board) Divide(depth int) map[string]uint64 {
+	divided := make(map[string]uint64)
+	if depth < 1 {
+		return divided
+	}
+
+	for _, move := range board.LegalMoves() {
+		undo, err := board.makeMove(move)
+		if err != nil {
+			panic(err)
+		}
+		divided[move.UCI()] = board.Perft(depth - 1)
+		board.UndoMove(undo)
+	}
+
+	return divided
+}
@@ -0,0 +1,59 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Reference counts from https://www.chessprogramming.org/Perft_Results
+var perftPositions = []struct {
+	name   string
+	fen    string
+	counts []uint64 // Expected nodes at depth 1, 2, ...
+}{
+	{"startpos", StartingFEN, []uint64{20, 400, 8902, 197281, 4865609}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862, 4085603}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238, 674624}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379, 2103487}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []uint64{46, 2079, 89890, 3894594}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, position := range perftPositions {
+		t.Run(position.name, func(t *testing.T) {
+			b, err := FromFEN(position.fen)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			for depth, expected := range position.counts {
+				if testing.Short() && expected > 100000 {
+					break
+				}
+				assert.Equal(t, expected, b.Perft(depth+1), "depth %d", depth+1)
+			}
+		})
+	}
+}
+
+func TestDivide(t *testing.T) {
+	divided := New().Divide(2)
+
+	assert.Len(t, divided, 20)
+	assert.Equal(t, uint64(20), divided["e2e4"])
+	assert.Equal(t, uint64(20), divided["g1f3"])
+}
+
+func TestPerftLeavesBoardUnchanged(t *testing.T) {
+	b, err := FromFEN(perftPositions[1].fen)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	before := b.ToFEN()
+	b.Perft(3)
+	assert.Equal(t, before, b.ToFEN())
+}
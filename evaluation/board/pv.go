@@ -0,0 +1,52 @@
+package board
+
+import "strings"
+
+// FormatPV writes a line of moves in UCI notation, separated by spaces.
+func FormatPV(pv []Move) string {
+	moves := make([]string, len(pv))
+	for i, move := range pv {
+		moves[i] = move.UCI()
+	}
+
+	return strings.Join(moves, " ")
+}
+
+// pvFromTT follows the best moves stored in the transposition table for up to length moves.
+// It stops at a missing entry, a move that is not legal here or a repeated position.
+func (board Board) pvFromTT(length int) []Move {
+	var pv []Move
+	seen := make(map[uint64]bool)
+
+	for len(pv) < length && !seen[board.Hash] {
+		seen[board.Hash] = true
+
+		entry, ok := TranspositionTable.Probe(board.Hash)
+		if !ok || entry.BestMove == 0 {
+			break
+		}
+
+		move, ok := board.findPackedMove(entry.BestMove)
+		if !ok {
+			break
+		}
+
+		if _, err := board.makeMove(move); err != nil {
+			panic(err)
+		}
+		pv = append(pv, move)
+	}
+
+	return pv
+}
+
+// findPackedMove looks the packed move up among the legal moves of the side to move.
+func (board Board) findPackedMove(packed PackedMove) (Move, bool) {
+	for _, move := range board.LegalMoves() {
+		if move.Pack() == packed {
+			return move, true
+		}
+	}
+
+	return Move{}, false
+}
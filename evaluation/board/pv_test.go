@@ -0,0 +1,58 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertLegalLine plays the line from the position, failing on the first illegal move.
+func assertLegalLine(t *testing.T, b Board, line []Move) {
+	for _, move := range line {
+		if !assert.Contains(t, b.LegalMoves(), move, "%s in %s", move.UCI(), b.ToFEN()) {
+			return
+		}
+		_, err := b.makeMove(move)
+		assert.NoError(t, err)
+	}
+}
+
+func TestSearchReturnsPrincipalVariation(t *testing.T) {
+	TranspositionTable.Clear()
+	b := New()
+
+	result := b.IterativeDeepening(4, TimeControl{}, nil, func(result SearchResult) {
+		assert.Equal(t, result.Move, result.PV[0])
+		assert.Len(t, result.PV, result.Depth)
+		assertLegalLine(t, b, result.PV)
+	}, 8, -3, -8, 1)
+
+	assert.Len(t, result.PV, 4)
+}
+
+func TestPrincipalVariationOfMate(t *testing.T) {
+	// Back rank mate in two: Re8+ Rxe8 Rxe8#
+	b, err := FromFEN("3r2k1/5ppp/8/8/8/8/4R3/4R1K1 w - - 0 1")
+	assert.NoError(t, err)
+
+	result := b.IterativeDeepening(3, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.Equal(t, MateScore-3, result.Score)
+	assert.Equal(t, "e2e8 d8e8 e1e8", FormatPV(result.PV))
+}
+
+func TestPVFromTT(t *testing.T) {
+	TranspositionTable.Clear()
+	b := New()
+
+	e4 := Move{Source: 12, Destination: 28, Piece: WhitePawn, CapturedPiece: -1, MoveType: NormalMove}
+	e5 := Move{Source: 52, Destination: 36, Piece: BlackPawn, CapturedPiece: -1, MoveType: NormalMove}
+	TranspositionTable.Store(b.Hash, TranspositionEntry{Depth: 2, Flag: exact, BestMove: e4.Pack()})
+
+	afterE4 := b
+	_, err := afterE4.makeMove(e4)
+	assert.NoError(t, err)
+	TranspositionTable.Store(afterE4.Hash, TranspositionEntry{Depth: 1, Flag: exact, BestMove: e5.Pack()})
+
+	assert.Equal(t, "e2e4 e7e5", FormatPV(b.pvFromTT(5)))
+	assert.Equal(t, "e2e4", FormatPV(b.pvFromTT(1)))
+}
@@ -0,0 +1,142 @@
+package board
+
+import "sort"
+
+// pieceValues are the material weights of Evaluate in pawns, indexed by piece. The king is given a large
+// value so it is the last piece to capture with.
+var pieceValues = [12]int16{1, 1, 3, 3, 3, 3, 5, 5, 9, 9, 100, 100}
+
+// deltaMargin is how many pawns a capture may gain on top of the captured material, e.g. through positional terms.
+const deltaMargin = 2
+
+// capturedValue returns the material a capture or promotion wins, in pawns.
+func capturedValue(move Move) int16 {
+	var value int16
+
+	switch {
+	case move.MoveType == EnPassant:
+		value = pieceValues[WhitePawn]
+	case move.CapturedPiece != -1:
+		value = pieceValues[move.CapturedPiece]
+	}
+
+	if move.MoveType == Promotion {
+		value += pieceValues[move.PromotionPiece] - pieceValues[WhitePawn]
+	}
+
+	return value
+}
+
+// orderMVVLVA sorts captures by most valuable victim first, then by least valuable attacker.
+func orderMVVLVA(moves []Move) {
+	sort.SliceStable(moves, func(i, j int) bool {
+		if gainI, gainJ := capturedValue(moves[i]), capturedValue(moves[j]); gainI != gainJ {
+			return gainI > gainJ
+		}
+		return pieceValues[moves[i].Piece] < pieceValues[moves[j].Piece]
+	})
+}
+
+// evaluate returns the static evaluation in centipawns for the side to move.
+func (s *search) evaluate(board *Board) int32 {
+	return board.Evaluate(s.materialModifier, s.mobilityModifier, s.centreModifier, s.penaltyModifier).Centipawns(s.materialModifier)
+}
+
+// quiescence keeps searching captures below the nominal depth until the position is quiet, so the search
+// does not stop in the middle of an exchange. The side to move may stand pat on the static evaluation instead
+// of capturing, unless it is in check, in which case every evasion is searched. With checks set quiet checks
+// are tried too, which is only done at the first ply.
+func (board *Board) quiescence(alpha, beta int32, ply int, checks bool, s *search) int32 {
+	s.selDepth = max(s.selDepth, ply)
+	if s.shouldStop() {
+		return 0
+	}
+	s.stats.quiescenceNodes.Add(1)
+	if ply >= maxPly {
+		return s.evaluate(board)
+	}
+
+	inCheck := board.inCheck()
+
+	var moves []Move
+	var bestScore, standPat int32
+	if inCheck {
+		moves = board.LegalMoves()
+
+		// Checkmated, there is no standing pat
+		bestScore = -MateScore + int32(ply)
+		if len(moves) == 0 {
+			return bestScore
+		}
+	} else {
+		standPat = s.evaluate(board)
+		if standPat >= beta {
+			return standPat
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		bestScore = standPat
+
+		moves = Captures(board)
+		orderMVVLVA(moves)
+		if checks {
+			moves = append(moves, board.quietChecks()...)
+		}
+	}
+
+	for _, move := range moves {
+		// Delta pruning: skip moves that cannot bring the score up to alpha even if they win a little extra
+		if !inCheck && standPat+int32(capturedValue(move)+deltaMargin)*100 <= alpha {
+			continue
+		}
+		// Captures that lose material in the exchange cannot help either
+		if !inCheck && isTactical(move) && board.SEE(move) < 0 {
+			continue
+		}
+
+		tmpBoard := *board
+		undo, err := tmpBoard.MakeNativeMove(move)
+		if err != nil {
+			panic(err)
+		}
+		score := -tmpBoard.quiescence(-beta, -alpha, ply+1, false, s)
+		tmpBoard.UndoMove(undo)
+		if s.aborted.Load() {
+			return 0
+		}
+
+		if score > bestScore {
+			bestScore = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	return bestScore
+}
+
+// quietChecks returns the legal moves that give check without winning material.
+func (board *Board) quietChecks() []Move {
+	var checks []Move
+
+	for _, move := range board.LegalMoves() {
+		if capturedValue(move) > 0 || move.MoveType == Promotion {
+			continue
+		}
+
+		tmpBoard := *board
+		if _, err := tmpBoard.makeMove(move); err != nil {
+			panic(err)
+		}
+		if tmpBoard.inCheck() {
+			checks = append(checks, move)
+		}
+	}
+
+	return checks
+}
@@ -0,0 +1,59 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuiescenceAvoidsDefendedPawn(t *testing.T) {
+	// Qxd5 wins a pawn on the surface but exd5 takes the queen back
+	b, err := FromFEN("4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1")
+	assert.NoError(t, err)
+
+	result := b.IterativeDeepening(1, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.NotEqual(t, "d1d5", result.Move.UCI())
+}
+
+func TestQuiescenceTakesHangingPiece(t *testing.T) {
+	b, err := FromFEN("4k3/8/8/3r4/8/8/8/3QK3 w - - 0 1")
+	assert.NoError(t, err)
+
+	result := b.IterativeDeepening(2, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.Equal(t, "d1d5", result.Move.UCI())
+}
+
+func TestCapturesOrderedByMVVLVA(t *testing.T) {
+	// The queen on d5 can be taken by the pawn or the rook, the knight on a5 by the rook
+	b, err := FromFEN("4k3/8/8/n2q4/4P3/8/8/3RK3 w - - 0 1")
+	assert.NoError(t, err)
+
+	moves := Captures(&b)
+	orderMVVLVA(moves)
+
+	var ucis []string
+	for _, move := range moves {
+		ucis = append(ucis, move.UCI())
+	}
+	assert.Equal(t, []string{"e4d5", "d1d5"}, ucis)
+}
+
+func TestCapturesIncludeEnPassantAndQueenPromotions(t *testing.T) {
+	b, err := FromFEN("4k3/1P6/8/3pP3/8/8/8/4K3 w - d6 0 1")
+	assert.NoError(t, err)
+
+	var ucis []string
+	for _, move := range Captures(&b) {
+		ucis = append(ucis, move.UCI())
+	}
+	assert.ElementsMatch(t, []string{"e5d6", "b7b8q"}, ucis)
+}
+
+func TestQuiescenceScoresCheckmate(t *testing.T) {
+	// Black to move is mated
+	b, err := FromFEN("R5k1/5ppp/8/8/8/8/8/4K3 b - - 0 1")
+	assert.NoError(t, err)
+
+	s := newSearch(DefaultSearchOptions(), 8, -3, -8, 1)
+	assert.Equal(t, -MateScore+3, b.quiescence(-Infinity, Infinity, 3, false, s))
+}
@@ -0,0 +1,405 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMovesToGo is the number of moves the remaining time is spread over when the clock gives none.
+const defaultMovesToGo = 30
+
+// TimeControl is the clock of the side to move.
+type TimeControl struct {
+	MoveTime  time.Duration // Fixed time for this move, overrides the clock
+	Remaining time.Duration
+	Increment time.Duration
+	MovesToGo int // Moves until the next time control, 0 for sudden death
+}
+
+// Budget returns how long to think about the move, or 0 when the search is not timed.
+func (tc TimeControl) Budget() time.Duration {
+	if tc.MoveTime > 0 {
+		return tc.MoveTime
+	}
+	if tc.Remaining <= 0 {
+		return 0
+	}
+
+	movesToGo := tc.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+
+	// Never risk more than half of the clock on one move
+	budget := tc.Remaining/time.Duration(movesToGo) + tc.Increment/2
+	if budget > tc.Remaining/2 {
+		budget = tc.Remaining / 2
+	}
+
+	return budget
+}
+
+// SearchLimits tell the search when to stop. Zero fields set no limit. Whichever limit is hit first ends
+// the search, but only once its first iteration completed so that there is always a move to play.
+type SearchLimits struct {
+	TimeControl                // The time budget, see TimeControl.Budget; MoveTime sets a fixed time for the move
+	Depth       int            // Deepest iteration to search
+	Nodes       uint64         // Nodes to search, counted over every thread
+	Mate        int            // Stop once a mate in this many moves or fewer is found for the side to move
+	Deadline    time.Time      // Time by which the search has to return, on top of the budget and the context's deadline
+	Options     *SearchOptions // Settings of the search, DefaultSearchOptions if nil
+}
+
+// SearchResult is the outcome of one completed iteration of the search.
+type SearchResult struct {
+	Depth int
+	Move  Move
+	Score int32            // Centipawns from the point of view of the side to move, see IsMateScore for mates
+	PV    []Move           // Principal variation, starting with Move
+	Lines []MoveEvaluation // The best SearchOptions.MultiPV lines, best first, the first one being Move, Score and PV
+	Nodes uint64           // Counted over every thread; the final result includes the iteration that was cut short
+	Stats SearchStats      // Counted over every iteration so far
+}
+
+// currMoveDelay is how long a search runs before it reports the root move it is searching, see SearchInfo.
+const currMoveDelay = time.Second
+
+// SearchInfo reports the progress of a search: once per line after every iteration, and whenever the search
+// moves on to the next root move once it has run for a while, in which case only CurrMove, CurrMoveNumber,
+// Depth, Nodes and Time are set.
+type SearchInfo struct {
+	Depth    int
+	SelDepth int // Deepest ply reached by the iteration, quiescence search included
+	MultiPV  int // Rank of the line among the SearchOptions.MultiPV best ones, from 1
+	Score    int32
+	PV       []Move
+	Nodes    uint64 // Counted over every thread
+	NPS      uint64
+	Hashfull int    // Permille of the transposition table in use
+	TBHits   uint64 // Endgame tablebase probes that hit, always 0 as there are no tablebases yet
+	Time     time.Duration
+
+	CurrMove       Move
+	CurrMoveNumber int // Position of CurrMove in the root move ordering, from 1
+}
+
+// String formats the information on one line for people to read.
+func (info SearchInfo) String() string {
+	if info.CurrMove.Source != info.CurrMove.Destination {
+		return fmt.Sprintf("depth %d currmove %s (%d)", info.Depth, info.CurrMove.UCI(), info.CurrMoveNumber)
+	}
+
+	score := fmt.Sprintf("%+.2f", float64(info.Score)/100)
+	if IsMateScore(info.Score) {
+		score = fmt.Sprintf("mate %d", MateIn(info.Score))
+	}
+	return fmt.Sprintf("depth %d/%d  %d. %-8s nodes %d  nps %d  hashfull %d  time %s  pv %s",
+		info.Depth, info.SelDepth, info.MultiPV, score, info.Nodes, info.NPS, info.Hashfull, info.Time.Round(time.Millisecond), FormatPV(info.PV))
+}
+
+// newInfo reports the progress of the search so far, the rest of the fields are left to the caller.
+func (s *search) newInfo(depth int) SearchInfo {
+	elapsed := time.Since(s.start)
+	nodes := s.nodes.Load()
+
+	info := SearchInfo{Depth: depth, Nodes: nodes, Time: elapsed}
+	if elapsed > 0 {
+		info.NPS = uint64(float64(nodes) / elapsed.Seconds())
+	}
+	return info
+}
+
+// SearchStats count what the search did, to compare how much of the tree each feature saves.
+type SearchStats struct {
+	QuiescenceNodes       uint64 // Nodes visited by the quiescence search, they are included in SearchResult.Nodes
+	BetaCutoffs           uint64
+	FirstMoveCutoffs      uint64 // Cut-offs on the first move searched, a measure of the move ordering
+	Researches            uint64 // Null window searches that failed high and had to be repeated
+	AspirationFailLows    uint64
+	AspirationFailHighs   uint64
+	NullMoveCutoffs       uint64 // Nodes pruned because passing the turn failed high
+	Reductions            uint64 // Late moves searched to a reduced depth
+	ReductionResearches   uint64 // Reduced searches that failed high and were repeated to full depth
+	FutilityPrunes        uint64
+	ReverseFutilityPrunes uint64
+	Extensions            uint64 // Moves searched one ply deeper, singular extensions included
+	SingularExtensions    uint64
+}
+
+// Add returns the sum of both statistics.
+func (stats SearchStats) Add(other SearchStats) SearchStats {
+	return SearchStats{
+		QuiescenceNodes:       stats.QuiescenceNodes + other.QuiescenceNodes,
+		BetaCutoffs:           stats.BetaCutoffs + other.BetaCutoffs,
+		FirstMoveCutoffs:      stats.FirstMoveCutoffs + other.FirstMoveCutoffs,
+		Researches:            stats.Researches + other.Researches,
+		AspirationFailLows:    stats.AspirationFailLows + other.AspirationFailLows,
+		AspirationFailHighs:   stats.AspirationFailHighs + other.AspirationFailHighs,
+		NullMoveCutoffs:       stats.NullMoveCutoffs + other.NullMoveCutoffs,
+		Reductions:            stats.Reductions + other.Reductions,
+		ReductionResearches:   stats.ReductionResearches + other.ReductionResearches,
+		FutilityPrunes:        stats.FutilityPrunes + other.FutilityPrunes,
+		ReverseFutilityPrunes: stats.ReverseFutilityPrunes + other.ReverseFutilityPrunes,
+		Extensions:            stats.Extensions + other.Extensions,
+		SingularExtensions:    stats.SingularExtensions + other.SingularExtensions,
+	}
+}
+
+// searchStats is the live, shared counterpart of SearchStats.
+type searchStats struct {
+	quiescenceNodes, betaCutoffs, firstMoveCutoffs, researches, aspirationFailLows, aspirationFailHighs atomic.Uint64
+	nullMoveCutoffs, reductions, reductionResearches, futilityPrunes, reverseFutilityPrunes             atomic.Uint64
+	extensions, singularExtensions                                                                      atomic.Uint64
+}
+
+func (stats *searchStats) snapshot() SearchStats {
+	return SearchStats{
+		QuiescenceNodes:       stats.quiescenceNodes.Load(),
+		BetaCutoffs:           stats.betaCutoffs.Load(),
+		FirstMoveCutoffs:      stats.firstMoveCutoffs.Load(),
+		Researches:            stats.researches.Load(),
+		AspirationFailLows:    stats.aspirationFailLows.Load(),
+		AspirationFailHighs:   stats.aspirationFailHighs.Load(),
+		NullMoveCutoffs:       stats.nullMoveCutoffs.Load(),
+		Reductions:            stats.reductions.Load(),
+		ReductionResearches:   stats.reductionResearches.Load(),
+		FutilityPrunes:        stats.futilityPrunes.Load(),
+		ReverseFutilityPrunes: stats.reverseFutilityPrunes.Load(),
+		Extensions:            stats.extensions.Load(),
+		SingularExtensions:    stats.singularExtensions.Load(),
+	}
+}
+
+// SearchOptions switch individual search features on and off.
+type SearchOptions struct {
+	QuiescenceChecks bool  // Also try quiet checking moves at the first ply of the quiescence search
+	MultiPV          int   // Number of best lines to find, each with its own score and principal variation
+	PVS              bool  // Search every move but the first with a null window, see negamax
+	AspirationWindow int32 // Half width in centipawns of the root window around the last score, 0 searches with a full window
+	NullMove         bool  // Prune nodes where passing the turn still fails high, see nullMovePrune
+	LMR              bool  // Search quiet moves late in the ordering to a reduced depth first
+	Futility         bool  // Skip quiet moves near the leaves when the static evaluation is far below alpha
+	ReverseFutility  bool  // Fail high near the leaves when the static evaluation is far above beta
+	Threads          int   // Number of goroutines searching the root together, see IterativeDeepening
+
+	Extensions         bool // Search checks, recaptures and passed pawn pushes one ply deeper, see extension
+	SingularExtensions bool // Search the hash move one ply deeper when every other move is clearly worse, see isSingular
+	ExtensionBudget    int  // Most plies a single path may be extended by
+}
+
+// DefaultSearchOptions returns the options of a search that is not given any, see SearchLimits. Each call
+// returns a fresh copy, which the caller is free to change.
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		QuiescenceChecks: true,
+		MultiPV:          1,
+		PVS:              true,
+		AspirationWindow: 50,
+		NullMove:         true,
+		LMR:              true,
+		Futility:         true,
+		ReverseFutility:  true,
+		Threads:          1,
+
+		Extensions:         true,
+		SingularExtensions: true,
+		ExtensionBudget:    8,
+	}
+}
+
+// search holds the settings and limits shared by every node of one search thread. Only the node count is
+// shared with the other threads of the same search.
+type search struct {
+	options SearchOptions
+	helper  int // 0 for the main thread, numbered from 1 for the helpers of a Lazy SMP search
+
+	materialModifier, mobilityModifier, centreModifier, penaltyModifier int8
+
+	start    time.Time
+	deadline time.Time       // Zero when the search is not timed
+	nodes    *atomic.Uint64  // Nodes visited by every thread of the search
+	maxNodes uint64          // Node limit, 0 for none
+	stop     <-chan struct{} // Closed by the caller to end the search early
+	aborted  atomic.Bool     // Set once a limit is hit, after which every node returns straight away
+
+	info     func(SearchInfo) // Called on progress of the main thread, nil when nobody listens
+	selDepth int              // Deepest ply reached during the current iteration
+
+	extensions [maxPly + 1]int        // Plies the path to each ply was extended by
+	excluded   [maxPly + 1]PackedMove // Move left out at each ply while testing the hash move for singularity
+
+	stats      searchStats
+	heuristics heuristics
+}
+
+// newSearch returns the state of a search thread with its own node counter and no limits.
+func newSearch(options SearchOptions, materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) *search {
+	return &search{options: options, materialModifier: materialModifier, mobilityModifier: mobilityModifier, centreModifier: centreModifier, penaltyModifier: penaltyModifier, nodes: new(atomic.Uint64)}
+}
+
+// shouldStop counts the node and reports whether the search has to be abandoned.
+// The clock and the stop channel are only looked at every few thousand nodes.
+func (s *search) shouldStop() bool {
+	nodes := s.nodes.Add(1)
+	if s.maxNodes > 0 && nodes >= s.maxNodes {
+		s.aborted.Store(true)
+	}
+
+	if nodes%2048 == 0 {
+		select {
+		case <-s.stop:
+			s.aborted.Store(true)
+		default:
+		}
+
+		if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+			s.aborted.Store(true)
+		}
+	}
+
+	return s.aborted.Load()
+}
+
+// IterativeDeepening searches one ply deeper at a time until maxDepth is reached, the time budget of the
+// clock runs out or stop is closed. It is Search with a stop channel instead of a context, calling report
+// with the result of every iteration.
+func (board *Board) IterativeDeepening(maxDepth int, clock TimeControl, stop <-chan struct{}, report func(SearchResult), materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) SearchResult {
+	return board.iterate(stop, time.Time{}, SearchLimits{TimeControl: clock, Depth: maxDepth}, report, nil, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+}
+
+// Search searches one ply deeper at a time until one of the limits is hit or the context is done, and returns
+// promptly with the last completed iteration when it is cancelled. Each iteration tries the best move of the
+// previous one first. An iteration cut short is thrown away; the first iteration always completes so there
+// is a move to play. info, if not nil, is called as the search goes, see SearchInfo. Moves are ordered by the
+// hash move and the killer, history and countermove heuristics, see movePicker.
+//
+// With SearchOptions.Threads above 1 the search is a Lazy SMP one: helper goroutines search the same
+// position alongside, see helperSearch. They only share the transposition table with the main thread,
+// whose results are the ones reported, and are stopped as soon as it returns.
+func (board *Board) Search(ctx context.Context, limits SearchLimits, info func(SearchInfo), materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) SearchResult {
+	deadline, _ := ctx.Deadline()
+	return board.iterate(ctx.Done(), deadline, limits, nil, info, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+}
+
+// iterate runs the iterations of Search, ending early once stop is closed or the deadline, if not zero, passes.
+// report and info are both optional.
+func (board *Board) iterate(stop <-chan struct{}, deadline time.Time, limits SearchLimits, report func(SearchResult), info func(SearchInfo), materialModifier, mobilityModifier, centreModifier, penaltyModifier int8) (result SearchResult) {
+	start := time.Now()
+	if budget := limits.Budget(); budget > 0 && (deadline.IsZero() || start.Add(budget).Before(deadline)) {
+		deadline = start.Add(budget)
+	}
+	if !limits.Deadline.IsZero() && (deadline.IsZero() || limits.Deadline.Before(deadline)) {
+		deadline = limits.Deadline
+	}
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > maxPly {
+		maxDepth = maxPly
+	}
+
+	options := DefaultSearchOptions()
+	if limits.Options != nil {
+		options = *limits.Options
+	}
+	s := newSearch(options, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+	s.start, s.info = start, info
+
+	TranspositionTable.NewSearch()
+
+	done := make(chan struct{})
+	var helpers sync.WaitGroup
+	for helper := 1; helper < s.options.Threads; helper++ {
+		h := newSearch(s.options, materialModifier, mobilityModifier, centreModifier, penaltyModifier)
+		h.helper, h.nodes, h.stop = helper, s.nodes, done
+		h.options.MultiPV = 1
+
+		// makeMove appends to the History of the board it is played on, so each helper gets a root of its own
+		root := *board
+		root.History = slices.Clone(board.History)
+
+		helpers.Add(1)
+		go func() {
+			defer helpers.Done()
+			root.helperSearch(h)
+		}()
+	}
+	defer helpers.Wait()
+	defer close(done)
+
+	// The nodes of the iteration that was cut short count too
+	defer func() { result.Nodes = s.nodes.Load() }()
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		// searchRoot searches one ply below every root move
+		s.selDepth = 0
+		lines, completed := board.searchLines(depth-1, s, result.Lines)
+		if !completed {
+			break
+		}
+
+		move := lines[0].Move
+		result = SearchResult{Depth: depth, Move: move, Score: lines[0].Score, PV: lines[0].PV, Lines: lines, Nodes: s.nodes.Load(), Stats: s.stats.snapshot()}
+		if report != nil {
+			report(result)
+		}
+		if info != nil {
+			iteration := s.newInfo(depth)
+			iteration.SelDepth, iteration.Hashfull = s.selDepth, TranspositionTable.Hashfull()
+			for i, line := range lines {
+				iteration.MultiPV, iteration.Score, iteration.PV = i+1, line.Score, line.PV
+				info(iteration)
+			}
+		}
+
+		// No legal moves, there is nothing to deepen
+		if move.Source == move.Destination {
+			break
+		}
+
+		if limits.Mate > 0 && IsMateScore(result.Score) && MateIn(result.Score) > 0 && MateIn(result.Score) <= limits.Mate {
+			break
+		}
+
+		// The limits only apply once there is a move to fall back on
+		s.stop = stop
+		s.maxNodes = limits.Nodes
+		if limits.Nodes > 0 && result.Nodes >= limits.Nodes {
+			break
+		}
+		if !deadline.IsZero() {
+			s.deadline = deadline
+
+			// The next iteration takes several times as long as this one, don't start it if it cannot finish
+			if time.Since(start) > deadline.Sub(start)/2 {
+				break
+			}
+		}
+
+		select {
+		case <-stop:
+			return result
+		default:
+		}
+	}
+
+	return result
+}
+
+// helperSearch deepens the position like the main thread of a Lazy SMP search until its stop channel is
+// closed, throwing its results away: what it leaves in the transposition table is what helps. Odd helpers
+// start one ply deeper and helpers order the root moves differently, so that they do not all walk the tree
+// in step with the main thread.
+func (board *Board) helperSearch(s *search) {
+	var previous []MoveEvaluation
+	for depth := 1 + s.helper%2; depth < maxPly; depth++ {
+		lines, completed := board.searchLines(depth-1, s, previous)
+		if !completed || lines[0].Move.Source == lines[0].Move.Destination {
+			return
+		}
+		previous = lines
+	}
+}
@@ -0,0 +1,434 @@
+package board
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeControlBudget(t *testing.T) {
+	assert.Equal(t, time.Duration(0), TimeControl{}.Budget())
+	assert.Equal(t, 250*time.Millisecond, TimeControl{MoveTime: 250 * time.Millisecond, Remaining: time.Minute}.Budget())
+
+	// The clock is spread over the moves to go, plus half the increment
+	assert.Equal(t, 2*time.Second, TimeControl{Remaining: time.Minute, MovesToGo: 30}.Budget())
+	assert.Equal(t, 3*time.Second, TimeControl{Remaining: time.Minute, Increment: 2 * time.Second, MovesToGo: 30}.Budget())
+	assert.Equal(t, 2*time.Second, TimeControl{Remaining: time.Minute}.Budget())
+
+	// Never more than half the remaining time
+	assert.Equal(t, 5*time.Second, TimeControl{Remaining: 10 * time.Second, Increment: 20 * time.Second, MovesToGo: 1}.Budget())
+}
+
+func TestIterativeDeepeningReportsEveryDepth(t *testing.T) {
+	b := New()
+
+	var depths []int
+	result := b.IterativeDeepening(3, TimeControl{}, nil, func(result SearchResult) {
+		depths = append(depths, result.Depth)
+	}, 8, -3, -8, 1)
+
+	assert.Equal(t, []int{1, 2, 3}, depths)
+	assert.Equal(t, 3, result.Depth)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+}
+
+func TestIterativeDeepeningStopsOnTime(t *testing.T) {
+	b := New()
+
+	start := time.Now()
+	result := b.IterativeDeepening(64, TimeControl{MoveTime: 300 * time.Millisecond}, nil, nil, 8, -3, -8, 1)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Less(t, result.Depth, 64)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+}
+
+func TestIterativeDeepeningStopsOnSignal(t *testing.T) {
+	b := New()
+	stop := make(chan struct{})
+
+	time.AfterFunc(200*time.Millisecond, func() { close(stop) })
+
+	start := time.Now()
+	result := b.IterativeDeepening(64, TimeControl{}, stop, nil, 8, -3, -8, 1)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.GreaterOrEqual(t, result.Depth, 1)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+}
+
+func TestSearchStopsOnCancel(t *testing.T) {
+	b := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := b.Search(ctx, SearchLimits{}, nil, 8, -3, -8, 1)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+
+	// A context that is already done still gets the first iteration
+	result = b.Search(ctx, SearchLimits{}, nil, 8, -3, -8, 1)
+	assert.Equal(t, 1, result.Depth)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	b.Search(ctx, SearchLimits{}, nil, 8, -3, -8, 1)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestSearchLimits(t *testing.T) {
+	b := New()
+
+	result := b.Search(context.Background(), SearchLimits{Nodes: 20000}, nil, 8, -3, -8, 1)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+	assert.GreaterOrEqual(t, result.Nodes, uint64(20000), "searches up to the node limit")
+	assert.LessOrEqual(t, result.Nodes, uint64(20000+2048), "stops soon after the node limit")
+
+	start := time.Now()
+	result = b.Search(context.Background(), SearchLimits{Deadline: start.Add(200 * time.Millisecond)}, nil, 8, -3, -8, 1)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Contains(t, b.LegalMoves(), result.Move)
+
+	// Ra8 mates at once, nothing deeper is searched once it is found
+	b, err := FromFEN("6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	result = b.Search(context.Background(), SearchLimits{Mate: 1, Depth: 10}, nil, 8, -3, -8, 1)
+	assert.Equal(t, "a1a8", result.Move.UCI())
+	assert.Equal(t, 1, MateIn(result.Score))
+	assert.Less(t, result.Depth, 10)
+}
+
+func TestConcurrentSearches(t *testing.T) {
+	options := DefaultSearchOptions()
+	options.MultiPV = 2
+	b := New()
+	alone := b.Search(context.Background(), SearchLimits{Depth: 1, Options: &options}, nil, 8, -3, -8, 1)
+	assert.Len(t, alone.Lines, 2)
+
+	// Another search running alongside neither adds to the node count nor changes the options
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan SearchResult)
+	go func() {
+		other := New()
+		done <- other.Search(ctx, SearchLimits{}, nil, 8, -3, -8, 1)
+	}()
+
+	for i := 0; i < 100; i++ {
+		result := b.Search(context.Background(), SearchLimits{Depth: 1, Options: &options}, nil, 8, -3, -8, 1)
+		assert.Equal(t, alone.Nodes, result.Nodes)
+		assert.Len(t, result.Lines, 2)
+	}
+
+	cancel()
+	other := <-done
+	assert.Len(t, other.Lines, DefaultSearchOptions().MultiPV)
+	assert.Positive(t, other.Nodes)
+}
+
+func TestSearchInfo(t *testing.T) {
+	options := DefaultSearchOptions()
+	options.MultiPV = 2
+
+	// The position is searched twice, the second time most lines end early on hash cut-offs
+	b := New()
+	b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, nil, 8, -3, -8, 1)
+
+	var infos []SearchInfo
+	b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, func(info SearchInfo) {
+		infos = append(infos, info)
+	}, 8, -3, -8, 1)
+
+	assert.Len(t, infos, 6, "two lines per iteration")
+	for i, info := range infos {
+		assert.Equal(t, i/2+1, info.Depth)
+		assert.Equal(t, i%2+1, info.MultiPV)
+		assert.GreaterOrEqual(t, info.SelDepth, info.Depth)
+		assert.NotEmpty(t, info.PV)
+		assert.Positive(t, info.Nodes)
+	}
+
+	// Root moves are reported once the search has run for a while
+	var currMoves int
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*currMoveDelay, cancel)
+	b.Search(ctx, SearchLimits{}, func(info SearchInfo) {
+		if info.CurrMove.Source != info.CurrMove.Destination {
+			currMoves++
+			assert.Contains(t, b.LegalMoves(), info.CurrMove)
+			assert.GreaterOrEqual(t, info.Time, currMoveDelay)
+			cancel()
+		}
+	}, 8, -3, -8, 1)
+	assert.Positive(t, currMoves)
+
+	assert.Equal(t, "depth 3/7  1. mate 2   nodes 100  nps 1000  hashfull 5  time 100ms  pv a1a8",
+		SearchInfo{Depth: 3, SelDepth: 7, MultiPV: 1, Score: MateScore - 3, PV: []Move{{Source: 0, Destination: 56}}, Nodes: 100, NPS: 1000, Hashfull: 5, Time: 100 * time.Millisecond}.String())
+}
+
+func TestSelDepthCountsHashCutoffs(t *testing.T) {
+	// Every reply to a root move ends on an exact entry of a six ply search
+	b := New()
+	TranspositionTable.Clear()
+	for _, move := range b.LegalMoves() {
+		after := b
+		_, err := after.makeMove(move)
+		assert.NoError(t, err)
+		TranspositionTable.Store(after.Hash, TranspositionEntry{Depth: 6, Flag: exact})
+	}
+
+	var selDepths []int
+	b.Search(context.Background(), SearchLimits{Depth: 3}, func(info SearchInfo) {
+		selDepths = append(selDepths, info.SelDepth)
+	}, 8, -3, -8, 1)
+
+	// The first iteration goes straight into the quiescence search, the others cut off one ply below the root
+	assert.Equal(t, []int{1, 7, 7}, selDepths)
+}
+
+func TestSearchScoresMateDistance(t *testing.T) {
+	// Ra8 mates at once
+	b, err := FromFEN("6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	result := b.IterativeDeepening(2, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.Equal(t, "a1a8", result.Move.UCI())
+	assert.Equal(t, MateScore-1, result.Score)
+	assert.True(t, IsMateScore(result.Score))
+	assert.Equal(t, 1, MateIn(result.Score))
+
+	// The side getting mated sees the mate coming
+	b, err = FromFEN("6k1/5ppp/8/8/8/8/8/R3K3 b - - 0 1")
+	assert.NoError(t, err)
+
+	result = b.IterativeDeepening(3, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.False(t, IsMateScore(result.Score), "black can make luft")
+}
+
+func TestSearchScoresAreFromSideToMove(t *testing.T) {
+	// White is a rook up, so the score is good for white and bad for black
+	white, err := FromFEN("4k3/pppp4/8/8/8/8/PPPP4/R3K3 w - - 0 1")
+	assert.NoError(t, err)
+	black, err := FromFEN("4k3/pppp4/8/8/8/8/PPPP4/R3K3 b - - 0 1")
+	assert.NoError(t, err)
+
+	whiteResult := white.IterativeDeepening(2, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	blackResult := black.IterativeDeepening(2, TimeControl{}, nil, nil, 8, -3, -8, 1)
+	assert.Greater(t, whiteResult.Score, int32(300))
+	assert.Less(t, blackResult.Score, int32(-300))
+}
+
+func TestNegamaxStoresBoundFlags(t *testing.T) {
+	TranspositionTable.Clear()
+	b := New()
+	s := newSearch(DefaultSearchOptions(), 8, -3, -8, 1)
+
+	score := b.negamax(2, -Infinity, Infinity, 0, Move{}, s, new([]Move))
+	entry, ok := TranspositionTable.Probe(b.Hash)
+	assert.True(t, ok)
+	assert.Equal(t, exact, entry.Flag)
+	assert.Equal(t, score, entry.Score)
+
+	// A window above the true score fails low and only proves an upper bound
+	TranspositionTable.Clear()
+	assert.LessOrEqual(t, b.negamax(2, score+100, score+200, 0, Move{}, s, new([]Move)), score+100)
+	entry, _ = TranspositionTable.Probe(b.Hash)
+	assert.Equal(t, upperBound, entry.Flag)
+
+	// A window below it fails high and only proves a lower bound
+	TranspositionTable.Clear()
+	assert.GreaterOrEqual(t, b.negamax(2, score-200, score-100, 0, Move{}, s, new([]Move)), score-100)
+	entry, _ = TranspositionTable.Probe(b.Hash)
+	assert.Equal(t, lowerBound, entry.Flag)
+}
+
+func TestMateIn(t *testing.T) {
+	assert.Equal(t, 1, MateIn(MateScore-1))
+	assert.Equal(t, 2, MateIn(MateScore-3))
+	assert.Equal(t, -1, MateIn(-MateScore+2))
+	assert.False(t, IsMateScore(900))
+}
+
+func TestMultiPVFindsDistinctRankedLines(t *testing.T) {
+	options := DefaultSearchOptions()
+	options.MultiPV = 3
+
+	b := New()
+	lines := map[int]int{}
+	result := b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, func(info SearchInfo) {
+		lines[info.Depth]++
+	}, 8, -3, -8, 1)
+
+	assert.Equal(t, map[int]int{1: 3, 2: 3, 3: 3}, lines, "three lines every iteration")
+	assert.Len(t, result.Lines, 3)
+	assert.Equal(t, result.Move, result.Lines[0].Move)
+	assert.Equal(t, result.Score, result.Lines[0].Score)
+	assert.Equal(t, result.PV, result.Lines[0].PV)
+
+	seen := map[string]bool{}
+	for i, line := range result.Lines {
+		assert.False(t, seen[line.Move.UCI()], "root moves are not repeated")
+		seen[line.Move.UCI()] = true
+		assert.Equal(t, line.Move, line.PV[0])
+		if i > 0 {
+			assert.LessOrEqual(t, line.Score, result.Lines[i-1].Score)
+		}
+	}
+}
+
+func TestMultiPVWithFewerLegalMoves(t *testing.T) {
+	options := DefaultSearchOptions()
+	options.MultiPV = 5
+
+	// The king in the corner only has three moves
+	b, err := FromFEN("k7/8/8/8/8/8/8/4K2R b - - 0 1")
+	assert.NoError(t, err)
+
+	result := b.Search(context.Background(), SearchLimits{Depth: 2, Options: &options}, nil, 8, -3, -8, 1)
+	assert.Len(t, result.Lines, len(b.LegalMoves()))
+}
+
+func TestPVSFindsTheSameResult(t *testing.T) {
+	for _, fen := range []string{"6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", "4k3/8/8/3r4/8/8/8/3QK3 w - - 0 1"} {
+		b, err := FromFEN(fen)
+		assert.NoError(t, err)
+
+		options := DefaultSearchOptions()
+		options.PVS = false
+		TranspositionTable.Clear()
+		full := b.Search(context.Background(), SearchLimits{Depth: 4, Options: &options}, nil, 8, -3, -8, 1)
+		assert.Zero(t, full.Stats.Researches)
+
+		options.PVS = true
+		TranspositionTable.Clear()
+		pvs := b.Search(context.Background(), SearchLimits{Depth: 4, Options: &options}, nil, 8, -3, -8, 1)
+
+		assert.Equal(t, full.Move, pvs.Move, fen)
+		if IsMateScore(full.Score) {
+			assert.Equal(t, full.Score, pvs.Score, fen)
+		}
+	}
+}
+
+func TestAspirationWindowWidensOnFailure(t *testing.T) {
+	b := New()
+	s := newSearch(DefaultSearchOptions(), 8, -3, -8, 1)
+
+	TranspositionTable.Clear()
+	exact, _ := b.searchRoot(3, s, Move{}, nil, -Infinity, Infinity)
+
+	// A guess far too high fails low, one far too low fails high, both end on the full window score
+	TranspositionTable.Clear()
+	low, completed := b.searchAspiration(3, s, MoveEvaluation{Move: exact.Move, Score: exact.Score + 1000}, nil)
+	assert.True(t, completed)
+	assert.Equal(t, exact.Score, low.Score)
+	assert.Greater(t, s.stats.aspirationFailLows.Load(), uint64(0))
+
+	TranspositionTable.Clear()
+	high, completed := b.searchAspiration(3, s, MoveEvaluation{Move: exact.Move, Score: exact.Score - 1000}, nil)
+	assert.True(t, completed)
+	assert.Equal(t, exact.Score, high.Score)
+	assert.Greater(t, s.stats.aspirationFailHighs.Load(), uint64(0))
+}
+
+func TestNullMovePruning(t *testing.T) {
+	b := New()
+	s := newSearch(DefaultSearchOptions(), 8, -3, -8, 1)
+
+	// A queen up, passing still fails high against a low beta
+	up, err := FromFEN("3qk3/8/8/8/8/8/8/3QKQ2 w - - 0 1")
+	assert.NoError(t, err)
+	TranspositionTable.Clear()
+	_, pruned := up.nullMovePrune(4, 0, s.evaluate(&up), 1, s)
+	assert.True(t, pruned)
+
+	// Not when the position is short of beta
+	TranspositionTable.Clear()
+	_, pruned = b.nullMovePrune(4, 1000, s.evaluate(&b), 1, s)
+	assert.False(t, pruned)
+
+	// Never with only pawns left, where zugzwang is common
+	pawns, err := FromFEN("8/8/4k3/3p4/3P4/4K3/8/8 w - - 0 1")
+	assert.NoError(t, err)
+	assert.False(t, pawns.hasNonPawnMaterial())
+	assert.True(t, up.hasNonPawnMaterial())
+}
+
+func TestLateMoveReduction(t *testing.T) {
+	assert.Zero(t, lateMoveReduction(3, 1, false))
+	assert.LessOrEqual(t, lateMoveReduction(8, 4, false), lateMoveReduction(8, 30, false))
+	assert.LessOrEqual(t, lateMoveReduction(4, 30, false), lateMoveReduction(12, 30, false))
+	assert.Equal(t, lateMoveReduction(12, 30, false)-1, lateMoveReduction(12, 30, true))
+
+	// At least one ply is always left
+	for depth := lmrMinDepth; depth < 80; depth++ {
+		assert.LessOrEqual(t, lateMoveReduction(depth, 200, false), depth-2)
+	}
+}
+
+func TestPruningKeepsTactics(t *testing.T) {
+	// The knight forks king and queen
+	b, err := FromFEN("q3k3/8/8/3N4/8/8/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	for _, options := range []SearchOptions{
+		{QuiescenceChecks: true, MultiPV: 1, PVS: true},
+		{QuiescenceChecks: true, MultiPV: 1, PVS: true, LMR: true},
+		{QuiescenceChecks: true, MultiPV: 1, PVS: true, Futility: true, ReverseFutility: true},
+		DefaultSearchOptions(),
+	} {
+		TranspositionTable.Clear()
+		result := b.Search(context.Background(), SearchLimits{Depth: 4, Options: &options}, nil, 8, -3, -8, 1)
+		assert.Equal(t, "d5c7", result.Move.UCI(), "%+v", options)
+	}
+}
+
+func TestLazySMP(t *testing.T) {
+	options := DefaultSearchOptions()
+	options.Threads = 4
+
+	// The knight forks king and queen
+	b, err := FromFEN("q3k3/8/8/3N4/8/8/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	goroutines := runtime.NumGoroutine()
+	TranspositionTable.Clear()
+	result := b.Search(context.Background(), SearchLimits{Depth: 4, Options: &options}, nil, 8, -3, -8, 1)
+	assert.Equal(t, 4, result.Depth)
+	assert.Equal(t, "d5c7", result.Move.UCI())
+
+	// The helpers are stopped before the search returns
+	assert.Equal(t, goroutines, runtime.NumGoroutine())
+
+	// and cancelling the search ends every thread
+	b = New()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result = b.Search(ctx, SearchLimits{Options: &options}, nil, 8, -3, -8, 1)
+	assert.NotEqual(t, Move{}, result.Move)
+	assert.Equal(t, goroutines, runtime.NumGoroutine())
+
+	// Every thread plays the en passant capture out on its own history, however much room the root's has left
+	b, err = FromFEN("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3")
+	assert.NoError(t, err)
+	b.History = make([]uint64, 0, 64)
+	result = b.Search(context.Background(), SearchLimits{Depth: 3, Options: &options}, nil, 8, -3, -8, 1)
+	assert.Equal(t, 3, result.Depth)
+	assert.Empty(t, b.History)
+}
+
+func TestHelperOrder(t *testing.T) {
+	moves := []Move{{Source: 0}, {Source: 1}, {Source: 2}, {Source: 3}}
+	helperOrder(moves, 1)
+	assert.Equal(t, []Move{{Source: 0}, {Source: 2}, {Source: 3}, {Source: 1}}, moves, "the first move stays first")
+
+	helperOrder(moves, 3)
+	assert.Equal(t, []Move{{Source: 0}, {Source: 2}, {Source: 3}, {Source: 1}}, moves, "a full turn changes nothing")
+}
@@ -0,0 +1,124 @@
+package board
+
+import (
+	"math/bits"
+
+	"engine/evaluation/board/bitboards"
+)
+
+// seeValue is the worth of a piece in centipawns during an exchange.
+func seeValue(piece int) int {
+	return int(pieceValues[piece]) * 100
+}
+
+// SEE returns the static exchange evaluation of a move in centipawns: the material the moving side wins or
+// loses once both sides have recaptured on the destination square, each with its least valuable attacker and
+// each free to stop when recapturing no longer pays. Sliders lined up behind a piece that captured join in
+// once it has left, so batteries count. A quiet move scores how much it loses by moving onto an attacked square.
+func (board *Board) SEE(move Move) int {
+	piece := move.Piece
+	if piece == -1 {
+		piece = board.PieceAt(move.Source)
+	}
+	black := piece%2 == 1
+
+	var gain [32]int
+	gain[0] = int(capturedValue(move)) * 100
+
+	// The piece left on the square is the next one to be taken
+	onSquare := seeValue(piece)
+	if move.MoveType == Promotion {
+		onSquare = seeValue(move.PromotionPiece)
+	}
+
+	occupied := board.OccupiedSquares &^ bitboards.New(move.Source)
+	if move.MoveType == EnPassant {
+		if black {
+			occupied &^= bitboards.New(move.Destination + 8)
+		} else {
+			occupied &^= bitboards.New(move.Destination - 8)
+		}
+	}
+
+	depth := 0
+	for side := !black; depth+1 < len(gain); side = !side {
+		// Pieces taken off the board keep their bits in the piece bitboards, occupied leaves them out
+		attackers := board.attackersTo(move.Destination, occupied) & occupied
+		square, capturer := board.leastValuableAttacker(attackers, side)
+		if capturer == -1 {
+			break
+		}
+
+		// The king can only recapture when nothing takes it back
+		if capturer == board.side(side).kingPiece {
+			without := occupied &^ bitboards.New(square)
+			if board.attackersTo(move.Destination, without)&without&board.side(!side).all != 0 {
+				break
+			}
+		}
+
+		depth++
+		gain[depth] = onSquare - gain[depth-1]
+		onSquare = seeValue(capturer)
+		occupied &^= bitboards.New(square)
+	}
+
+	// Either side stops capturing as soon as going on is worse for it
+	for ; depth > 0; depth-- {
+		gain[depth-1] = -max(-gain[depth-1], gain[depth])
+	}
+
+	return gain[0]
+}
+
+// hangingPenalty is what each hanging piece costs in the evaluation, a quarter of a pawn at the usual material modifier of 8.
+const hangingPenalty = 2
+
+// hangingPieces counts the pieces of the given side, pawns and king left out, that the other side wins
+// material against by taking them with its cheapest attacker, see SEE. Attacked pieces that are defended
+// well enough do not count.
+func (board *Board) hangingPieces(black bool) int {
+	us := board.side(black)
+
+	count := 0
+	pieces := us.all &^ us.pawns &^ us.king
+	for pieces != 0 {
+		square := int(pieces.PopLSB())
+		attackers := board.attackersTo(square, board.OccupiedSquares) & board.side(!black).all
+		from, capturer := board.leastValuableAttacker(attackers, !black)
+		if capturer == -1 {
+			continue
+		}
+
+		capture := Move{Source: from, Destination: square, Piece: capturer, CapturedPiece: board.PieceAt(square)}
+		if board.SEE(capture) > 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+// leastValuableAttacker returns the square and piece of the cheapest of the attackers of the given side,
+// or -1 for the piece when it has none.
+func (board *Board) leastValuableAttacker(attackers bitboards.BitBoard, black bool) (int, int) {
+	side := board.side(black)
+
+	for _, candidate := range []struct {
+		pieces bitboards.BitBoard
+		piece  int
+	}{
+		{side.pawns, side.pawn},
+		{side.knights, side.knight},
+		{side.bishops, side.bishop},
+		{side.rooks, side.rook},
+		{side.queens, side.queen},
+		{side.king, side.kingPiece},
+	} {
+		if found := attackers & candidate.pieces; found != 0 {
+			return bits.TrailingZeros64(uint64(found)), candidate.piece
+		}
+	}
+
+	return 0, -1
+}
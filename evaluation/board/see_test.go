@@ -0,0 +1,83 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func findMove(t *testing.T, b Board, uci string) Move {
+	t.Helper()
+	for _, move := range b.LegalMoves() {
+		if move.UCI() == uci {
+			return move
+		}
+	}
+	t.Fatalf("%s is not legal in %s", uci, b.ToFEN())
+	return Move{}
+}
+
+func TestSEE(t *testing.T) {
+	for _, test := range []struct {
+		fen, move string
+		see       int
+	}{
+		// Free pawn
+		{"4k3/8/8/3p4/8/8/8/3RK3 w - - 0 1", "d1d5", 100},
+		// The rook takes a defended pawn and is taken back
+		{"4k3/8/4p3/3p4/8/8/8/3RK3 w - - 0 1", "d1d5", 100 - 500},
+		// The pawn takes a defended knight and is taken back
+		{"4k3/8/4p3/3n4/4P3/8/8/4K3 w - - 0 1", "e4d5", 300 - 100},
+		// The queen behind the rook wins the pawn back
+		{"4k3/8/4p3/3n4/8/8/3R4/3QK3 w - - 0 1", "d2d5", 300 - 500 + 100},
+		// The x-raying queen takes the bishop that took back
+		{"4k3/8/8/3n4/4b3/8/3R4/3QK3 w - - 0 1", "d2d5", 300 - 500 + 300},
+		// Without the battery the rook is lost for the knight
+		{"4k3/8/8/3n4/4b3/8/3R4/4K3 w - - 0 1", "d2d5", 300 - 500},
+		// The king cannot take back a defended piece, but can an undefended one
+		{"r6k/8/8/8/8/1b6/P7/K7 b - - 0 1", "a8a2", 100},
+		{"r6k/8/8/8/8/8/P7/K7 b - - 0 1", "a8a2", 100 - 500},
+		// En passant wins a pawn
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		// A quiet move onto a square attacked by a pawn loses the piece
+		{"4k3/8/8/8/1p6/8/8/3NK3 w - - 0 1", "d1c3", -300},
+		{"4k3/8/8/8/1p6/8/8/3NK3 w - - 0 1", "d1e3", 0},
+	} {
+		b, err := FromFEN(test.fen)
+		assert.NoError(t, err)
+		assert.Equal(t, test.see, b.SEE(findMove(t, b, test.move)), test.fen)
+	}
+}
+
+func TestHangingPieces(t *testing.T) {
+	for _, test := range []struct {
+		fen          string
+		white, black int
+	}{
+		// The knight is attacked by the rook and nothing defends it
+		{"4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1", 0, 1},
+		// Defended by a pawn, taking it loses the rook
+		{"4k3/8/4p3/3n4/8/8/8/3RK3 w - - 0 1", 0, 0},
+		// A pawn wins the defended knight all the same
+		{"4k3/8/4p3/3n4/4P3/8/8/4K3 w - - 0 1", 0, 1},
+		// The rooks attack each other, whoever is to move
+		{"4k3/8/8/r6R/8/8/8/4K3 w - - 0 1", 1, 1},
+		{"4k3/8/8/r6R/8/8/8/4K3 b - - 0 1", 1, 1},
+		// until one of them is defended
+		{"4k3/8/8/r6R/6P1/8/8/4K3 b - - 0 1", 0, 1},
+	} {
+		b, err := FromFEN(test.fen)
+		assert.NoError(t, err)
+		assert.Equal(t, test.white, b.hangingPieces(false), test.fen)
+		assert.Equal(t, test.black, b.hangingPieces(true), test.fen)
+	}
+
+	// The evaluation only holds an undefended piece against its side
+	hanging, err := FromFEN("4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(hangingPenalty), hanging.Evaluate(8, -3, -8, 1).hanging)
+
+	defended, err := FromFEN("4k3/8/4p3/3n4/8/8/8/3RK3 w - - 0 1")
+	assert.NoError(t, err)
+	assert.Zero(t, defended.Evaluate(8, -3, -8, 1).hanging)
+}
@@ -0,0 +1,223 @@
+package board
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// DefaultHashSize is the size of the transposition table in megabytes.
+const DefaultHashSize = 16
+
+const (
+	bucketSize = 4  // Slots sharing one index
+	slotBytes  = 16 // Size of a ttSlot
+)
+
+// Bound types, 0 marks an empty slot
+const (
+	exact = iota + 1
+	lowerBound
+	upperBound
+)
+
+// PackedMove holds a move in 16 bits: source in bits 0-5, destination in bits 6-11
+// and the promotion piece kind in bits 12-14. The null move packs to 0.
+type PackedMove uint16
+
+func (m Move) Pack() PackedMove {
+	packed := PackedMove(m.Source) | PackedMove(m.Destination)<<6
+	if m.MoveType == Promotion {
+		packed |= PackedMove(m.PromotionPiece/2) << 12
+	}
+
+	return packed
+}
+
+type TranspositionEntry struct {
+	Depth    int
+	Score    int32 // Mate scores count the plies from this position, not from the root
+	Flag     int
+	BestMove PackedMove
+}
+
+// ttSlot is one entry of the table. The key is stored XORed with the data, so a slot torn by
+// concurrent writers no longer matches its hash and is ignored instead of being read back as garbage.
+type ttSlot struct {
+	key  atomic.Uint64 // hash ^ data
+	data atomic.Uint64 // Move in bits 0-15, depth in 16-23, bound in 24-25, age in 26-31, score in 32-63
+}
+
+type ttBucket [bucketSize]ttSlot
+
+// HashTable is a fixed-size hash table of search results shared by all search goroutines without locking.
+type HashTable struct {
+	buckets []ttBucket
+	age     atomic.Uint32 // Bumped by NewSearch so entries of earlier searches are replaced first
+}
+
+// TranspositionTable is shared by every search.
+var TranspositionTable = NewHashTable(DefaultHashSize)
+
+// NewHashTable allocates a table using about the given number of megabytes.
+func NewHashTable(megabytes int) *HashTable {
+	tt := &HashTable{}
+	tt.Resize(megabytes)
+	return tt
+}
+
+// Resize reallocates the table, dropping every entry. It must not be called during a search.
+func (tt *HashTable) Resize(megabytes int) {
+	if megabytes < 1 {
+		megabytes = 1
+	}
+
+	count := megabytes * 1024 * 1024 / (bucketSize * slotBytes)
+	tt.buckets = make([]ttBucket, count)
+}
+
+// Megabytes returns the size of the table.
+func (tt *HashTable) Megabytes() int {
+	return len(tt.buckets) * bucketSize * slotBytes / (1024 * 1024)
+}
+
+// Clear drops every entry. It must not be called during a search.
+func (tt *HashTable) Clear() {
+	tt.buckets = make([]ttBucket, len(tt.buckets))
+	tt.age.Store(0)
+}
+
+// NewSearch ages the entries already in the table.
+func (tt *HashTable) NewSearch() {
+	tt.age.Add(1)
+}
+
+// ageMask keeps the age within its 6 bits
+const ageMask = 63
+
+func (tt *HashTable) currentAge() uint8 {
+	return uint8(tt.age.Load()) & ageMask
+}
+
+func (tt *HashTable) bucket(hash uint64) *ttBucket {
+	// Map the hash onto the table without needing a power of two size
+	index, _ := bits.Mul64(hash, uint64(len(tt.buckets)))
+	return &tt.buckets[index]
+}
+
+// Probe looks the position up, reporting false when it is not in the table.
+func (tt *HashTable) Probe(hash uint64) (TranspositionEntry, bool) {
+	bucket := tt.bucket(hash)
+
+	for i := range bucket {
+		slot := &bucket[i]
+		data := slot.data.Load()
+		if data != 0 && slot.key.Load()^data == hash {
+			return unpackEntry(data), true
+		}
+	}
+
+	return TranspositionEntry{}, false
+}
+
+// Store saves a search result. A slot already holding the position is reused, wherever it is in the
+// bucket, otherwise an empty slot or else the shallowest and oldest one is replaced.
+func (tt *HashTable) Store(hash uint64, entry TranspositionEntry) {
+	bucket := tt.bucket(hash)
+	age := tt.currentAge()
+
+	for i := range bucket {
+		slot := &bucket[i]
+		data := slot.data.Load()
+		if data == 0 || slot.key.Load()^data != hash {
+			continue
+		}
+
+		// Keep a deeper bound found earlier in this search
+		if entry.Flag != exact && entryAge(data) == age && entryDepth(data) > entry.Depth {
+			return
+		}
+		if entry.BestMove == 0 {
+			entry.BestMove = PackedMove(data)
+		}
+		tt.write(slot, hash, entry, age)
+		return
+	}
+
+	replace := &bucket[0]
+	lowestValue := math.MaxInt
+	for i := range bucket {
+		slot := &bucket[i]
+		data := slot.data.Load()
+		if data == 0 {
+			replace = slot
+			break
+		}
+
+		// Every search since the entry was stored costs it as much as a few plies of depth
+		value := entryDepth(data) - 8*int((age-entryAge(data))&ageMask)
+		if value < lowestValue {
+			lowestValue = value
+			replace = slot
+		}
+	}
+
+	tt.write(replace, hash, entry, age)
+}
+
+// write puts the entry into the slot.
+func (tt *HashTable) write(slot *ttSlot, hash uint64, entry TranspositionEntry, age uint8) {
+	data := packEntry(entry, age)
+	slot.key.Store(hash ^ data)
+	slot.data.Store(data)
+}
+
+// Hashfull returns how full the table is in permille, counting entries of the current search in a sample of slots.
+func (tt *HashTable) Hashfull() int {
+	age := tt.currentAge()
+	sampled, used := 0, 0
+
+	for i := 0; i < len(tt.buckets) && sampled < 1000; i++ {
+		for j := range tt.buckets[i] {
+			data := tt.buckets[i][j].data.Load()
+			if data != 0 && entryAge(data) == age {
+				used++
+			}
+			sampled++
+		}
+	}
+
+	if sampled == 0 {
+		return 0
+	}
+
+	return used * 1000 / sampled
+}
+
+func packEntry(entry TranspositionEntry, age uint8) uint64 {
+	depth := entry.Depth
+	if depth < 0 {
+		depth = 0
+	} else if depth > math.MaxUint8 {
+		depth = math.MaxUint8
+	}
+
+	return uint64(entry.BestMove) | uint64(depth)<<16 | uint64(entry.Flag&3)<<24 | uint64(age&ageMask)<<26 | uint64(uint32(entry.Score))<<32
+}
+
+func unpackEntry(data uint64) TranspositionEntry {
+	return TranspositionEntry{
+		Depth:    entryDepth(data),
+		Score:    int32(data >> 32),
+		Flag:     int(data>>24) & 3,
+		BestMove: PackedMove(data),
+	}
+}
+
+func entryDepth(data uint64) int {
+	return int(uint8(data >> 16))
+}
+
+func entryAge(data uint64) uint8 {
+	return uint8(data>>26) & ageMask
+}
@@ -0,0 +1,141 @@
+package board
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranspositionTableStoreAndProbe(t *testing.T) {
+	tt := NewHashTable(1)
+	entry := TranspositionEntry{Depth: 7, Score: -31990, Flag: lowerBound, BestMove: Move{Source: 12, Destination: 28}.Pack()}
+
+	tt.Store(0xDEADBEEF, entry)
+
+	found, ok := tt.Probe(0xDEADBEEF)
+	assert.True(t, ok)
+	assert.Equal(t, entry, found)
+
+	_, ok = tt.Probe(0xFEEDFACE)
+	assert.False(t, ok)
+
+	tt.Clear()
+	_, ok = tt.Probe(0xDEADBEEF)
+	assert.False(t, ok)
+}
+
+func TestPackMove(t *testing.T) {
+	assert.Equal(t, PackedMove(0), Move{}.Pack())
+	assert.Equal(t, PackedMove(12|28<<6), Move{Source: 12, Destination: 28, MoveType: NormalMove}.Pack())
+
+	// Promotions to different pieces pack differently
+	queen := Move{Source: 52, Destination: 60, MoveType: Promotion, PromotionPiece: WhiteQueen}.Pack()
+	knight := Move{Source: 52, Destination: 60, MoveType: Promotion, PromotionPiece: WhiteKnight}.Pack()
+	assert.NotEqual(t, queen, knight)
+	assert.Equal(t, queen, Move{Source: 52, Destination: 60, MoveType: Promotion, PromotionPiece: BlackQueen}.Pack())
+}
+
+func TestTranspositionTableReplacement(t *testing.T) {
+	tt := NewHashTable(1)
+	hash := uint64(0x123456789)
+
+	// A shallower bound does not overwrite a deeper one from the same search, an exact score does
+	tt.Store(hash, TranspositionEntry{Depth: 6, Flag: lowerBound, BestMove: 100})
+	tt.Store(hash, TranspositionEntry{Depth: 2, Flag: upperBound})
+	found, _ := tt.Probe(hash)
+	assert.Equal(t, 6, found.Depth)
+
+	tt.Store(hash, TranspositionEntry{Depth: 2, Flag: exact})
+	found, _ = tt.Probe(hash)
+	assert.Equal(t, 2, found.Depth)
+	assert.Equal(t, PackedMove(100), found.BestMove, "the best move is kept when the new entry has none")
+
+	// Once the bucket is full the shallowest entry makes room
+	tt.Clear()
+	for i := 0; i < bucketSize; i++ {
+		tt.Store(hash+uint64(i), TranspositionEntry{Depth: 10 + i, Flag: exact})
+	}
+	tt.Store(hash+bucketSize, TranspositionEntry{Depth: 1, Flag: exact})
+
+	_, ok := tt.Probe(hash)
+	assert.False(t, ok)
+	for i := 1; i <= bucketSize; i++ {
+		_, ok := tt.Probe(hash + uint64(i))
+		assert.True(t, ok)
+	}
+
+	// A position behind an empty slot, as concurrent writers can leave it, is still updated in place
+	tt.Clear()
+	stale := packEntry(TranspositionEntry{Depth: 3, Flag: exact}, tt.currentAge())
+	tt.bucket(hash)[1].key.Store(hash ^ stale)
+	tt.bucket(hash)[1].data.Store(stale)
+	tt.Store(hash, TranspositionEntry{Depth: 8, Flag: exact})
+
+	holding := 0
+	for i := range tt.bucket(hash) {
+		if data := tt.bucket(hash)[i].data.Load(); data != 0 && tt.bucket(hash)[i].key.Load()^data == hash {
+			holding++
+		}
+	}
+	assert.Equal(t, 1, holding, "one slot per position")
+	found, _ = tt.Probe(hash)
+	assert.Equal(t, 8, found.Depth)
+
+	// Entries from earlier searches are replaced before deeper but current ones
+	tt.Clear()
+	for i := 0; i <= bucketSize; i++ {
+		tt.Store(hash+uint64(i), TranspositionEntry{Depth: 10 + i, Flag: exact})
+	}
+	tt.NewSearch()
+	tt.NewSearch()
+	tt.Store(hash+bucketSize+1, TranspositionEntry{Depth: 1, Flag: exact})
+	_, ok = tt.Probe(hash + bucketSize + 1)
+	assert.True(t, ok)
+}
+
+func TestTranspositionTableResize(t *testing.T) {
+	tt := NewHashTable(2)
+	assert.Equal(t, 2, tt.Megabytes())
+
+	tt.Store(0xDEADBEEF, TranspositionEntry{Depth: 5, Flag: exact})
+	tt.Resize(4)
+	assert.Equal(t, 4, tt.Megabytes())
+	_, ok := tt.Probe(0xDEADBEEF)
+	assert.False(t, ok, "resizing drops the entries")
+}
+
+func TestTranspositionTableHashfull(t *testing.T) {
+	tt := NewHashTable(1)
+	assert.Equal(t, 0, tt.Hashfull())
+
+	for i := uint64(0); i < 1<<16; i++ {
+		tt.Store(i*0x9E3779B97F4A7C15, TranspositionEntry{Depth: 1, Flag: exact})
+	}
+	assert.Greater(t, tt.Hashfull(), 0)
+
+	// Entries of earlier searches do not count
+	tt.NewSearch()
+	assert.Equal(t, 0, tt.Hashfull())
+}
+
+func TestTranspositionTableConcurrentAccess(t *testing.T) {
+	tt := NewHashTable(1)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				// Every writer stores a score tied to its hash, so any entry read back must match
+				hash := uint64(i%500)*0x9E3779B97F4A7C15 + 1
+				tt.Store(hash, TranspositionEntry{Depth: worker, Flag: exact, Score: int32(hash)})
+				if found, ok := tt.Probe(hash); ok {
+					assert.Equal(t, int32(hash), found.Score)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
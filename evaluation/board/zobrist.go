@@ -0,0 +1,94 @@
+package board
+
+import (
+	"math/bits"
+	"math/rand"
+
+	"engine/evaluation/board/bitboards"
+)
+
+// zobristSeed is fixed so hashes are the same across searches and runs, which keeps
+// transposition entries valid from one move to the next.
+const zobristSeed = 0x5EED2024
+
+const (
+	castleWhiteKingsideKey = iota
+	castleWhiteQueensideKey
+	castleBlackKingsideKey
+	castleBlackQueensideKey
+)
+
+var (
+	zobristPieces    [12][64]uint64 // One key per piece per square
+	zobristBlackTurn uint64         // Toggled when black is to move
+	zobristCastling  [4]uint64      // One key per castling right
+	zobristEnPassant [8]uint64      // One key per en passant file
+)
+
+func init() {
+	random := rand.New(rand.NewSource(zobristSeed))
+
+	for piece := range zobristPieces {
+		for square := range zobristPieces[piece] {
+			zobristPieces[piece][square] = random.Uint64()
+		}
+	}
+
+	zobristBlackTurn = random.Uint64()
+
+	for right := range zobristCastling {
+		zobristCastling[right] = random.Uint64()
+	}
+
+	for file := range zobristEnPassant {
+		zobristEnPassant[file] = random.Uint64()
+	}
+}
+
+// computeHash hashes the whole position from scratch. makeMove and UndoMove keep Board.Hash
+// up to date incrementally, so this is only needed when a board is set up.
+func (board Board) computeHash() uint64 {
+	var hash uint64
+
+	for piece := WhitePawn; piece <= BlackKing; piece++ {
+		pieces := *board.pieceBitboard(piece)
+		for pieces != 0 {
+			hash ^= zobristPieces[piece][pieces.PopLSB()]
+		}
+	}
+
+	if board.TurnBlack {
+		hash ^= zobristBlackTurn
+	}
+
+	return hash ^ board.castlingHash() ^ enPassantHash(board.EnPassantTarget)
+}
+
+// castlingHash returns the combined keys of the castling rights still available.
+func (board Board) castlingHash() uint64 {
+	var hash uint64
+
+	if board.CastleWhiteKingside {
+		hash ^= zobristCastling[castleWhiteKingsideKey]
+	}
+	if board.CastleWhiteQueenside {
+		hash ^= zobristCastling[castleWhiteQueensideKey]
+	}
+	if board.CastleBlackKingside {
+		hash ^= zobristCastling[castleBlackKingsideKey]
+	}
+	if board.CastleBlackQueenside {
+		hash ^= zobristCastling[castleBlackQueensideKey]
+	}
+
+	return hash
+}
+
+// enPassantHash returns the key of the en passant file, or 0 when there is no target square.
+func enPassantHash(target bitboards.BitBoard) uint64 {
+	if target == 0 {
+		return 0
+	}
+
+	return zobristEnPassant[bits.TrailingZeros64(uint64(target))%8]
+}
@@ -0,0 +1,95 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkIncrementalHash walks the move tree and compares the incremental hash to a full rehash after every move and undo.
+func checkIncrementalHash(t *testing.T, b Board, depth int) {
+	if depth == 0 {
+		return
+	}
+
+	for _, move := range b.LegalMoves() {
+		before := b.Hash
+
+		undo, err := b.makeMove(move)
+		assert.NoError(t, err)
+		if !assert.Equal(t, b.computeHash(), b.Hash, "after %s in %s", move.UCI(), b.ToFEN()) {
+			return
+		}
+
+		checkIncrementalHash(t, b, depth-1)
+
+		b.UndoMove(undo)
+		assert.Equal(t, before, b.Hash)
+	}
+}
+
+func TestIncrementalHashMatchesFullHash(t *testing.T) {
+	fens := []string{
+		StartingFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	}
+
+	for _, fen := range fens {
+		b, err := FromFEN(fen)
+		if assert.NoError(t, err) {
+			checkIncrementalHash(t, b, 3)
+		}
+	}
+}
+
+func TestHashIdentifiesPositions(t *testing.T) {
+	// The same position reached by two move orders hashes the same
+	first, second := New(), New()
+	for _, move := range []string{"g1f3", "g8f6", "b1c3"} {
+		assert.NoError(t, first.MakeUCIMove(move))
+	}
+	for _, move := range []string{"b1c3", "g8f6", "g1f3"} {
+		assert.NoError(t, second.MakeUCIMove(move))
+	}
+	assert.Equal(t, first.Hash, second.Hash)
+
+	// Side to move, castling rights and the en passant file all change the hash
+	fens := []string{
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w Kkq - 0 2",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+	}
+	hashes := map[uint64]string{}
+	for _, fen := range fens {
+		b, err := FromFEN(fen)
+		if assert.NoError(t, err) {
+			assert.NotContains(t, hashes, b.Hash, fen)
+			hashes[b.Hash] = fen
+		}
+	}
+
+	// The keys are seeded, so a position always hashes the same
+	assert.Equal(t, New().computeHash(), New().Hash)
+	assert.NotZero(t, New().Hash)
+}
+
+func TestNullMoveHash(t *testing.T) {
+	b, err := FromFEN("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2")
+	assert.NoError(t, err)
+	before := b
+
+	undo := b.MakeNullMove()
+	assert.False(t, b.TurnBlack)
+	assert.Zero(t, b.EnPassantTarget)
+	assert.Equal(t, b.computeHash(), b.Hash)
+
+	assert.Equal(t, []uint64{before.Hash}, b.History)
+
+	b.UndoNullMove(undo)
+	assert.Empty(t, b.History)
+	b.History = nil
+	assert.Equal(t, before, b)
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,65 +21,190 @@ import (
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("No mode specified")
-		fmt.Println("Usage: go run main.go [engine-vs-engine | engine-vs-human] [debug | no-debug] [depth]")
+		fmt.Println("Usage: go run main.go [engine-vs-engine | engine-vs-human] [debug | no-debug] [depth] [movetime-ms]")
+		fmt.Println("       go run main.go uci")
+		fmt.Println("       go run main.go perft [depth] [fen]")
+		fmt.Println("       go run main.go analyse [lines] [depth] [fen]")
+		fmt.Println("       go run main.go mate [moves] [fen]")
+		fmt.Println("       go run main.go bench [depth] [threads]")
 		os.Exit(1)
 	}
 
+	mode := os.Args[1]
+
+	if mode == "uci" {
+		runUCI(os.Stdin, os.Stdout)
+		return
+	}
+
+	if mode == "perft" {
+		if len(os.Args) < 3 {
+			fmt.Println("Missing depth. Usage: go run main.go perft [depth] [fen]")
+			os.Exit(1)
+		}
+		depth, err := strconv.Atoi(os.Args[2])
+		if err != nil || depth < 1 {
+			fmt.Println("Invalid depth:", os.Args[2])
+			os.Exit(1)
+		}
+		fen := board.StartingFEN
+		if len(os.Args) > 3 {
+			fen = strings.Join(os.Args[3:], " ")
+		}
+		if err := runPerft(depth, fen); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if mode == "analyse" {
+		if len(os.Args) < 4 {
+			fmt.Println("Missing arguments. Usage: go run main.go analyse [lines] [depth] [fen]")
+			os.Exit(1)
+		}
+		lines, err := strconv.Atoi(os.Args[2])
+		if err != nil || lines < 1 {
+			fmt.Println("Invalid number of lines:", os.Args[2])
+			os.Exit(1)
+		}
+		depth, err := strconv.Atoi(os.Args[3])
+		if err != nil || depth < 1 {
+			fmt.Println("Invalid depth:", os.Args[3])
+			os.Exit(1)
+		}
+		fen := board.StartingFEN
+		if len(os.Args) > 4 {
+			fen = strings.Join(os.Args[4:], " ")
+		}
+		if err := runAnalysis(lines, depth, fen); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if mode == "mate" {
+		if len(os.Args) < 3 {
+			fmt.Println("Missing moves. Usage: go run main.go mate [moves] [fen]")
+			os.Exit(1)
+		}
+		moves, err := strconv.Atoi(os.Args[2])
+		if err != nil || moves < 1 {
+			fmt.Println("Invalid number of moves:", os.Args[2])
+			os.Exit(1)
+		}
+		// The starting position has no forced mate, so the position has to be given
+		if len(os.Args) < 4 {
+			fmt.Println("Missing FEN. Usage: go run main.go mate [moves] [fen]")
+			os.Exit(1)
+		}
+		if err := runMate(moves, strings.Join(os.Args[3:], " ")); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if mode == "bench" {
+		depth := 5
+		if len(os.Args) > 2 {
+			var err error
+			depth, err = strconv.Atoi(os.Args[2])
+			if err != nil || depth < 1 {
+				fmt.Println("Invalid depth:", os.Args[2])
+				os.Exit(1)
+			}
+		}
+		threads := 1
+		if len(os.Args) > 3 {
+			var err error
+			threads, err = strconv.Atoi(os.Args[3])
+			if err != nil || threads < 1 {
+				fmt.Println("Invalid number of threads:", os.Args[3])
+				os.Exit(1)
+			}
+		}
+		runBench(depth, threads)
+		return
+	}
+
 	if len(os.Args) < 4 {
-		fmt.Println("Missing arguments. Usage: go run main.go [engine-vs-engine | engine-vs-human] [debug | no-debug] [depth]")
+		fmt.Println("Missing arguments. Usage: go run main.go [engine-vs-engine | engine-vs-human] [debug | no-debug] [depth] [movetime-ms]")
 		os.Exit(1)
 	}
 
-	mode := os.Args[1]
-
 	debug := os.Args[2]
 
 	depth, _ := strconv.Atoi(os.Args[3])
-
-	depth = depth - 2
-	if depth == 0 {
+	if depth <= 0 {
 		depth = 4
 	}
 
+	// An optional time per move, the search stops at whichever of depth and time runs out first
+	var clock board.TimeControl
+	if len(os.Args) > 4 {
+		moveTime, err := strconv.Atoi(os.Args[4])
+		if err != nil || moveTime <= 0 {
+			fmt.Println("Invalid move time:", os.Args[4])
+			os.Exit(1)
+		}
+		clock.MoveTime = time.Duration(moveTime) * time.Millisecond
+	}
+
+	// Games are played with every core searching
+	options := board.DefaultSearchOptions()
+	options.Threads = runtime.NumCPU()
+
 	switch mode {
 	case "engine-vs-engine":
-		playEngineVsEngine(debug, depth)
+		playEngineVsEngine(debug, depth, clock, options)
 	case "engine-vs-human":
-		playEngineVsHuman(debug, depth)
+		playEngineVsHuman(debug, depth, clock, options)
 	default:
 		fmt.Println("Invalid mode specified")
-		fmt.Println("Usage: go run main.go [engine-vs-engine | engine-vs-human] [debug | no-debug] [depth]")
+		fmt.Println("Usage: go run main.go [engine-vs-engine | engine-vs-human] [debug | no-debug] [depth] [movetime-ms]")
 		os.Exit(1)
 	}
 }
 
-func playEngineVsEngine(debug string, depth int) {
+func playEngineVsEngine(debug string, depth int, clock board.TimeControl, options board.SearchOptions) {
 	bitboards.InitBitboards()
-	board.TranspositionTable = make(map[uint64]board.TranspositionEntry)
-	board.InitZobristTable()
+	board.TranspositionTable.Clear()
 	b := board.New()
 
 	if debug == "debug" {
 		b.Debug = true
 	}
 
-	for {
-		err := b.MakeMove(depth)
+	for !gameOver(&b) {
+		err := b.MakeTimedMove(depth, clock, options)
 		if err != nil {
-			break
+			fmt.Println(err)
+			return
 		}
 		b.Display()
 	}
 }
 
-func playEngineVsHuman(debug string, depth int) {
+// gameOver prints the result and the reason once the game has ended.
+func gameOver(b *board.Board) bool {
+	state := b.GameState()
+	if state == board.InProgress {
+		return false
+	}
+
+	fmt.Printf("%s (%s)\n", b.Result(), state)
+	return true
+}
+
+func playEngineVsHuman(debug string, depth int, clock board.TimeControl, options board.SearchOptions) {
 	b := board.New()
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("Enter moves in standard chess notation (e.g., 'e2e4'), type 'exit' to quit:")
 	bitboards.InitBitboards()
-	board.TranspositionTable = make(map[uint64]board.TranspositionEntry)
-	board.InitZobristTable()
+	board.TranspositionTable.Clear()
 
 	if debug == "debug" {
 		b.Debug = true
@@ -110,9 +238,159 @@ func playEngineVsHuman(debug string, depth int) {
 
 		fmt.Println("Move made:", text)
 		// b.Display() // Assuming there's a function to display the board state
+		if gameOver(&b) {
+			return
+		}
 
-		b.MakeMove(depth)
+		b.MakeTimedMove(depth, clock, options)
 		b.Display()
+		if gameOver(&b) {
+			return
+		}
+	}
+}
+
+func runPerft(depth int, fen string) error {
+	bitboards.InitBitboards()
+	b, err := board.FromFEN(fen)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	divided := b.Divide(depth)
+
+	moves := make([]string, 0, len(divided))
+	for move := range divided {
+		moves = append(moves, move)
+	}
+	sort.Strings(moves)
+
+	var total uint64
+	for _, move := range moves {
+		fmt.Printf("%s: %d\n", move, divided[move])
+		total += divided[move]
+	}
+
+	fmt.Println()
+	fmt.Println("Nodes searched:", total)
+	log.Printf("Perft took %s", time.Since(start))
+
+	return nil
+}
+
+// runAnalysis prints the best lines of the position at every depth.
+func runAnalysis(lines, depth int, fen string) error {
+	bitboards.InitBitboards()
+	b, err := board.FromFEN(fen)
+	if err != nil {
+		return err
+	}
+
+	options := board.DefaultSearchOptions()
+	options.MultiPV = lines
+	b.Search(context.Background(), board.SearchLimits{Depth: depth, Options: &options}, func(info board.SearchInfo) {
+		fmt.Println(info)
+	}, 8, -3, -8, 1)
+
+	return nil
+}
+
+// runMate looks for a forced mate in at most the given number of moves and prints the mating line.
+func runMate(moves int, fen string) error {
+	bitboards.InitBitboards()
+	b, err := board.FromFEN(fen)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	result, err := b.SolveMate(context.Background(), moves)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	if result.Moves == 0 {
+		fmt.Printf("No mate in %d\n", moves)
+	} else {
+		fmt.Printf("Mate in %d: %s\n", result.Moves, board.FormatPV(result.PV))
+	}
+	fmt.Printf("Nodes: %d, time: %s\n", result.Nodes, elapsed.Round(time.Millisecond))
+
+	return nil
+}
+
+// benchPositions are searched by the bench command, a mix of openings, middlegames and endgames.
+var benchPositions = []string{
+	board.StartingFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bqkbnr/pppp1ppp/2n5/4p3/3PP3/5N2/PPP2PPP/RNBQKB1R b KQkq - 0 3",
+	"r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 8",
+	"2r3k1/pp3ppp/4p3/3pP3/3P4/P4N2/1P3PPP/2R3K1 w - - 0 25",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"8/8/4k3/3p4/3P4/4K3/8/8 w - - 0 1",
+}
+
+// benchConfiguration switches a search feature on, as it is set in the defaults.
+type benchConfiguration struct {
+	name   string
+	enable func(options, defaults *board.SearchOptions)
+}
+
+// benchConfigurations are compared by the bench command, each one adding its feature to the ones before it.
+var benchConfigurations = []benchConfiguration{
+	{"alpha-beta", func(options, defaults *board.SearchOptions) {}},
+	{"+pvs", func(options, defaults *board.SearchOptions) { options.PVS = defaults.PVS }},
+	{"+aspiration", func(options, defaults *board.SearchOptions) { options.AspirationWindow = defaults.AspirationWindow }},
+	{"+null move", func(options, defaults *board.SearchOptions) { options.NullMove = defaults.NullMove }},
+	{"+lmr", func(options, defaults *board.SearchOptions) { options.LMR = defaults.LMR }},
+	{"+futility", func(options, defaults *board.SearchOptions) {
+		options.Futility = defaults.Futility
+		options.ReverseFutility = defaults.ReverseFutility
+	}},
+	{"+extensions", func(options, defaults *board.SearchOptions) {
+		options.Extensions = defaults.Extensions
+		options.SingularExtensions = defaults.SingularExtensions
+		options.ExtensionBudget = defaults.ExtensionBudget
+	}},
+}
+
+// runBench searches every bench position to the given depth with each configuration and prints the node counts,
+// so the effect of a search feature can be measured. With more than one thread the helpers of the Lazy SMP search
+// race the main thread, so counts vary between runs.
+func runBench(depth, threads int) {
+	bitboards.InitBitboards()
+	defaults := board.DefaultSearchOptions()
+	options := board.SearchOptions{QuiescenceChecks: defaults.QuiescenceChecks, MultiPV: 1, Threads: threads}
+
+	for _, configuration := range benchConfigurations {
+		configuration.enable(&options, &defaults)
+
+		var nodes uint64
+		var stats board.SearchStats
+		start := time.Now()
+		for _, fen := range benchPositions {
+			b, err := board.FromFEN(fen)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			board.TranspositionTable.Clear()
+			result := b.Search(context.Background(), board.SearchLimits{Depth: depth, Options: &options}, nil, 8, -3, -8, 1)
+			nodes += result.Nodes
+			stats = stats.Add(result.Stats)
+		}
+		elapsed := time.Since(start)
+
+		fmt.Printf("%-12s nodes %10d  qnodes %10d  time %8s  nps %8.0f\n", configuration.name, nodes, stats.QuiescenceNodes, elapsed.Round(time.Millisecond), float64(nodes)/elapsed.Seconds())
+		var ordering float64
+		if stats.BetaCutoffs > 0 {
+			ordering = 100 * float64(stats.FirstMoveCutoffs) / float64(stats.BetaCutoffs)
+		}
+		fmt.Printf("%-12s cut-offs %d (%.1f%% on the first move)  re-searches %d  aspiration fails low %d high %d\n", "", stats.BetaCutoffs, ordering, stats.Researches, stats.AspirationFailLows, stats.AspirationFailHighs)
+		fmt.Printf("%-12s null move cut-offs %d  reductions %d (%d re-searched)  futility prunes %d  reverse futility prunes %d\n", "", stats.NullMoveCutoffs, stats.Reductions, stats.ReductionResearches, stats.FutilityPrunes, stats.ReverseFutilityPrunes)
+		fmt.Printf("%-12s extensions %d (%d singular)\n", "", stats.Extensions, stats.SingularExtensions)
 	}
 }
 
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"engine/evaluation/board"
+	"engine/evaluation/board/bitboards"
+)
+
+const (
+	defaultUCIDepth = 5
+	maxUCIDepth     = 12
+	maxUCIHash      = 1024 // Megabytes
+	maxUCIMultiPV   = 64
+	maxUCIThreads   = 256
+)
+
+// uciSession holds the state of one UCI conversation with a GUI.
+type uciSession struct {
+	board board.Board
+	depth int // Depth used when "go" carries no depth or clock limits
+
+	options board.SearchOptions // Set by setoption, every search gets a copy of them
+
+	out     io.Writer
+	outLock sync.Mutex
+
+	cancel context.CancelFunc // Ends the running search
+	done   chan struct{}      // Closed once the running search printed its bestmove
+}
+
+// goParams are the limits sent with a "go" command.
+type goParams struct {
+	depth     int
+	moveTime  time.Duration
+	whiteTime time.Duration
+	blackTime time.Duration
+	whiteInc  time.Duration
+	blackInc  time.Duration
+	movesToGo int
+	nodes     uint64
+	mate      int // Look for a mate in this many moves
+	infinite  bool
+}
+
+func runUCI(in io.Reader, out io.Writer) {
+	bitboards.InitBitboards()
+	board.TranspositionTable.Resize(board.DefaultHashSize)
+	options := board.DefaultSearchOptions()
+	options.MultiPV, options.Threads = 1, 1
+
+	session := &uciSession{board: board.New(), depth: defaultUCIDepth, options: options, out: out}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			session.send("id name ChessEngine")
+			session.send("id author Chefbutt")
+			session.send("option name Depth type spin default %d min 1 max %d", defaultUCIDepth, maxUCIDepth)
+			session.send("option name Hash type spin default %d min 1 max %d", board.DefaultHashSize, maxUCIHash)
+			session.send("option name MultiPV type spin default 1 min 1 max %d", maxUCIMultiPV)
+			session.send("option name Threads type spin default 1 min 1 max %d", maxUCIThreads)
+			session.send("uciok")
+		case "isready":
+			session.send("readyok")
+		case "ucinewgame":
+			session.stopSearch()
+			board.TranspositionTable.Clear()
+			session.board = board.New()
+		case "position":
+			session.stopSearch()
+			session.position(fields[1:])
+		case "go":
+			session.stopSearch()
+			session.goSearch(parseGoParams(fields[1:]))
+		case "stop":
+			session.stopSearch()
+		case "setoption":
+			session.stopSearch()
+			session.setOption(fields[1:])
+		case "quit":
+			session.stopSearch()
+			return
+		default:
+			session.send("info string unknown command %s", fields[0])
+		}
+	}
+
+	session.stopSearch()
+}
+
+func (s *uciSession) send(format string, args ...any) {
+	s.outLock.Lock()
+	defer s.outLock.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// position handles "position [startpos | fen <fen>] [moves <move>...]". The position is only taken over
+// when every move is legal, otherwise the session keeps the one it had.
+func (s *uciSession) position(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	var moves []string
+	for i, arg := range args {
+		if arg == "moves" {
+			moves = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	if len(args) == 0 {
+		s.send("info string missing position before moves")
+		return
+	}
+
+	var b board.Board
+	switch args[0] {
+	case "startpos":
+		b = board.New()
+	case "fen":
+		var err error
+		b, err = board.FromFEN(strings.Join(args[1:], " "))
+		if err != nil {
+			s.send("info string %v", err)
+			return
+		}
+	default:
+		s.send("info string unknown position %s", args[0])
+		return
+	}
+
+	for _, move := range moves {
+		if err := b.MakeUCIMove(move); err != nil {
+			s.send("info string %v", err)
+			return
+		}
+	}
+	s.board = b
+}
+
+// setOption handles "setoption name <id> [value <x>]".
+func (s *uciSession) setOption(args []string) {
+	var name, value string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "name":
+			if i+1 < len(args) {
+				name = args[i+1]
+				i++
+			}
+		case "value":
+			if i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+		}
+	}
+
+	switch strings.ToLower(name) {
+	case "depth":
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 1 || depth > maxUCIDepth {
+			s.send("info string invalid depth %s", value)
+			return
+		}
+		s.depth = depth
+	case "hash":
+		megabytes, err := strconv.Atoi(value)
+		if err != nil || megabytes < 1 || megabytes > maxUCIHash {
+			s.send("info string invalid hash size %s", value)
+			return
+		}
+		board.TranspositionTable.Resize(megabytes)
+	case "multipv":
+		lines, err := strconv.Atoi(value)
+		if err != nil || lines < 1 || lines > maxUCIMultiPV {
+			s.send("info string invalid multipv %s", value)
+			return
+		}
+		s.options.MultiPV = lines
+	case "threads":
+		threads, err := strconv.Atoi(value)
+		if err != nil || threads < 1 || threads > maxUCIThreads {
+			s.send("info string invalid threads %s", value)
+			return
+		}
+		s.options.Threads = threads
+	default:
+		s.send("info string unknown option %s", name)
+	}
+}
+
+func parseGoParams(args []string) goParams {
+	var params goParams
+
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			params.infinite = true
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+
+		value, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+
+		switch args[i] {
+		case "depth":
+			params.depth = value
+		case "movetime":
+			params.moveTime = time.Duration(value) * time.Millisecond
+		case "wtime":
+			params.whiteTime = time.Duration(value) * time.Millisecond
+		case "btime":
+			params.blackTime = time.Duration(value) * time.Millisecond
+		case "winc":
+			params.whiteInc = time.Duration(value) * time.Millisecond
+		case "binc":
+			params.blackInc = time.Duration(value) * time.Millisecond
+		case "movestogo":
+			params.movesToGo = value
+		case "nodes":
+			params.nodes = uint64(value)
+		case "mate":
+			params.mate = value
+		default:
+			continue
+		}
+		i++
+	}
+
+	return params
+}
+
+// timeControl returns the clock of the side to move.
+func (p goParams) timeControl(turnBlack bool) board.TimeControl {
+	clock := board.TimeControl{MoveTime: p.moveTime, Remaining: p.whiteTime, Increment: p.whiteInc, MovesToGo: p.movesToGo}
+	if turnBlack {
+		clock.Remaining, clock.Increment = p.blackTime, p.blackInc
+	}
+
+	return clock
+}
+
+// limits returns the search limits of the side to move, maxDepth being the depth to stop at or 0 for none.
+func (p goParams) limits(turnBlack bool, maxDepth int) board.SearchLimits {
+	return board.SearchLimits{TimeControl: p.timeControl(turnBlack), Depth: maxDepth, Nodes: p.nodes, Mate: p.mate}
+}
+
+func (s *uciSession) goSearch(params goParams) {
+	maxDepth := s.depth
+	if params.depth > 0 {
+		maxDepth = params.depth
+	} else if params.infinite || params.nodes > 0 || params.timeControl(s.board.TurnBlack).Budget() > 0 {
+		// The other limits end the search, it may go as deep as the engine can
+		maxDepth = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.search(ctx, s.board, s.options, params, maxDepth, s.done)
+}
+
+// stopSearch ends the running search, if any, and waits for its bestmove.
+func (s *uciSession) stopSearch() {
+	if s.done == nil {
+		return
+	}
+
+	s.cancel()
+	<-s.done
+	s.cancel, s.done = nil, nil
+}
+
+// search deepens one ply at a time until the depth, node or time limit is reached or ctx is cancelled by
+// a stop command, then plays the best move of the last completed iteration.
+func (s *uciSession) search(ctx context.Context, b board.Board, options board.SearchOptions, params goParams, maxDepth int, done chan struct{}) {
+	defer close(done)
+
+	if len(b.LegalMoves()) == 0 {
+		if params.infinite {
+			<-ctx.Done()
+		}
+		s.send("bestmove 0000")
+		return
+	}
+
+	move, found := s.solveMate(ctx, b, params.mate)
+	if !found {
+		limits := params.limits(b.TurnBlack, maxDepth)
+		limits.Options = &options
+		move = b.Search(ctx, limits, func(info board.SearchInfo) { s.info(info, options.MultiPV > 1) }, 8, -3, -8, 1).Move
+	}
+
+	// An infinite search only ends on stop, even when the depth limit was reached
+	if params.infinite {
+		<-ctx.Done()
+	}
+	s.send("bestmove %s", move.UCI())
+}
+
+// solveMate handles "go mate <moves>": it looks for a forced mate with the mate solver and reports the mating
+// line. When there is none, or the search was stopped first, the caller falls back on the normal search.
+func (s *uciSession) solveMate(ctx context.Context, b board.Board, moves int) (board.Move, bool) {
+	if moves <= 0 {
+		return board.Move{}, false
+	}
+
+	start := time.Now()
+	result, err := b.SolveMate(ctx, moves)
+	if err != nil {
+		return board.Move{}, false
+	}
+	if result.Moves == 0 {
+		s.send("info string no mate in %d", moves)
+		return board.Move{}, false
+	}
+
+	s.send("info depth %d nodes %d time %d score mate %d pv %s", 2*result.Moves-1, result.Nodes, time.Since(start).Milliseconds(), result.Moves, board.FormatPV(result.PV))
+	return result.PV[0], true
+}
+
+// info sends the progress of the search. The rank of a line is only given when several are searched.
+func (s *uciSession) info(info board.SearchInfo, multiPV bool) {
+	if info.CurrMove.Source != info.CurrMove.Destination {
+		s.send("info depth %d currmove %s currmovenumber %d", info.Depth, info.CurrMove.UCI(), info.CurrMoveNumber)
+		return
+	}
+
+	rank := ""
+	if multiPV {
+		rank = fmt.Sprintf(" multipv %d", info.MultiPV)
+	}
+	s.send("info depth %d seldepth %d%s score %s nodes %d nps %d hashfull %d tbhits %d time %d pv %s",
+		info.Depth, info.SelDepth, rank, uciScore(info.Score), info.Nodes, info.NPS, info.Hashfull, info.TBHits, info.Time.Milliseconds(), board.FormatPV(info.PV))
+}
+
+// uciScore formats a search score as "cp <centipawns>" or "mate <moves>".
+func uciScore(score int32) string {
+	if board.IsMateScore(score) {
+		return fmt.Sprintf("mate %d", board.MateIn(score))
+	}
+	return fmt.Sprintf("cp %d", score)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"engine/evaluation/board"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUCIPositionWithoutBoard(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader("position moves e2e4\nposition moves\nisready\nquit\n"), &out)
+
+	assert.Equal(t, 2, strings.Count(out.String(), "info string missing position before moves"))
+	assert.Contains(t, out.String(), "readyok")
+}
+
+func TestUCIOptionsStayInTheSession(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader("setoption name MultiPV value 2\nsetoption name Threads value 2\ngo depth 2\nisready\nquit\n"), &out)
+
+	assert.Contains(t, out.String(), " multipv 2 ")
+	assert.Contains(t, out.String(), "bestmove ")
+
+	// A new session starts from the defaults again
+	out.Reset()
+	runUCI(strings.NewReader("go depth 2\nisready\nquit\n"), &out)
+	assert.NotContains(t, out.String(), "multipv")
+	assert.Contains(t, out.String(), "bestmove ")
+}
+
+func TestUCIHashOption(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader("setoption name Hash value 4\nisready\nquit\n"), &out)
+	assert.Equal(t, 4, board.TranspositionTable.Megabytes())
+
+	// An invalid size leaves the table as it is
+	out.Reset()
+	runUCI(strings.NewReader("setoption name Hash value 4096\nisready\nquit\n"), &out)
+	assert.Contains(t, out.String(), "info string invalid hash size 4096")
+	assert.Equal(t, board.DefaultHashSize, board.TranspositionTable.Megabytes())
+}
+
+// uciBestMove returns the move of the last bestmove line of a session.
+func uciBestMove(t *testing.T, output string) string {
+	i := strings.LastIndex(output, "bestmove ")
+	if !assert.GreaterOrEqual(t, i, 0, "no bestmove in %q", output) {
+		return ""
+	}
+	return strings.Fields(output[i:])[1]
+}
+
+// uciLegalMoves returns the legal moves after playing moves from the position, in UCI notation.
+func uciLegalMoves(t *testing.T, fen string, moves ...string) []string {
+	b, err := board.FromFEN(fen)
+	assert.NoError(t, err)
+	for _, move := range moves {
+		assert.NoError(t, b.MakeUCIMove(move))
+	}
+
+	var legal []string
+	for _, move := range b.LegalMoves() {
+		legal = append(legal, move.UCI())
+	}
+	return legal
+}
+
+func TestUCIPositionWithMoves(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader("position startpos moves e2e4 e7e5\ngo depth 2\nisready\nquit\n"), &out)
+
+	assert.Contains(t, uciLegalMoves(t, board.StartingFEN, "e2e4", "e7e5"), uciBestMove(t, out.String()))
+}
+
+func TestUCIPositionWithIllegalMove(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader("position startpos moves e2e4 e7e5\nposition startpos moves d2d4 d2d4\ngo depth 1\nisready\nquit\n"), &out)
+
+	// None of the moves is played, the session stays in the position it had
+	assert.Contains(t, out.String(), "info string illegal move d2d4")
+	assert.Contains(t, uciLegalMoves(t, board.StartingFEN, "e2e4", "e7e5"), uciBestMove(t, out.String()))
+}
+
+func TestUCIGoInfiniteUntilStop(t *testing.T) {
+	var out bytes.Buffer
+	runUCI(strings.NewReader("position startpos\ngo infinite\nstop\nisready\nquit\n"), &out)
+
+	assert.Equal(t, 1, strings.Count(out.String(), "bestmove "))
+	assert.Less(t, strings.Index(out.String(), "bestmove "), strings.Index(out.String(), "readyok"), "stop waits for the bestmove")
+	assert.Contains(t, uciLegalMoves(t, board.StartingFEN), uciBestMove(t, out.String()))
+}
+
+// bestMoveWriter collects the output of a session and closes bestMove once a bestmove has been sent.
+type bestMoveWriter struct {
+	bytes.Buffer
+	bestMove chan struct{}
+	once     sync.Once
+}
+
+func (w *bestMoveWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("bestmove ")) {
+		defer w.once.Do(func() { close(w.bestMove) })
+	}
+	return w.Buffer.Write(p)
+}
+
+// runUCIUntilBestMove runs a session on the commands and only quits once the search they start has ended by itself.
+func runUCIUntilBestMove(commands string) string {
+	in, commandWriter := io.Pipe()
+	out := &bestMoveWriter{bestMove: make(chan struct{})}
+	go func() {
+		io.WriteString(commandWriter, commands)
+		<-out.bestMove
+		io.WriteString(commandWriter, "quit\n")
+	}()
+
+	runUCI(in, out)
+	return out.String()
+}
+
+func TestUCIGoNodesIsNotCappedInDepth(t *testing.T) {
+	// With only the kings left every line is a dead draw, the search gets deep on few nodes
+	output := runUCIUntilBestMove("position fen 7k/8/8/8/8/8/8/K7 w - - 0 1\ngo nodes 200000\n")
+	assert.Contains(t, output, fmt.Sprintf("info depth %d ", maxUCIDepth+1))
+
+	// Without a limit the search stops at the default depth
+	output = runUCIUntilBestMove("position fen 7k/8/8/8/8/8/8/K7 w - - 0 1\ngo\n")
+	assert.Contains(t, output, fmt.Sprintf("info depth %d ", defaultUCIDepth))
+	assert.NotContains(t, output, fmt.Sprintf("info depth %d ", defaultUCIDepth+1))
+}
+
+func TestUCIGoMate(t *testing.T) {
+	// Rd8+ Rxd8 Rxd8#
+	output := runUCIUntilBestMove("position fen 2r3k1/5ppp/8/8/8/8/3R1PPP/3R2K1 w - - 0 1\ngo mate 2\n")
+	assert.Contains(t, output, "score mate 2 pv d2d8 c8d8 d1d8")
+	assert.Equal(t, "d2d8", uciBestMove(t, output))
+
+	// Without a mate in the limit the normal search still finds a move
+	output = runUCIUntilBestMove("position fen 2r3k1/5ppp/8/8/8/8/3R1PPP/3R2K1 w - - 0 1\ngo mate 1\n")
+	assert.Contains(t, output, "info string no mate in 1")
+	assert.Contains(t, uciLegalMoves(t, "2r3k1/5ppp/8/8/8/8/3R1PPP/3R2K1 w - - 0 1"), uciBestMove(t, output))
+}